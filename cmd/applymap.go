@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/MSmaili/renym/internal/cli"
+	"github.com/MSmaili/renym/internal/common"
+	"github.com/MSmaili/renym/internal/engine"
+	"github.com/MSmaili/renym/internal/fs"
+	"github.com/MSmaili/renym/internal/mapping"
+	"github.com/spf13/cobra"
+)
+
+var applyMapCmd = &cobra.Command{
+	Use:   "apply-map <file>",
+	Short: "Rename files from a CSV, TSV or JSON mapping file",
+	Long: `Rename files from explicit old → new pairs listed in a mapping file.
+
+Supported formats, picked by file extension:
+  .csv    two columns: old,new (an "old,new" header row is optional)
+  .tsv    two tab-separated columns: old, new
+  .json   [{"old": "a.txt", "new": "b.txt"}] or {"a.txt": "b.txt"}
+
+Relative paths are resolved against --path. A new value without a
+directory keeps the file in its current directory. The renames go
+through the same collision checks, history and undo as the rename modes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApplyMap,
+	Example: `  # Apply a mapping from a spreadsheet export
+  renym apply-map renames.csv
+
+  # Resolve the paths in the mapping against another directory
+  renym apply-map renames.json -p ./photos
+
+  # Preview the renames first
+  renym apply-map renames.tsv --dry-run`,
+}
+
+func init() {
+	rootCmd.AddCommand(applyMapCmd)
+
+	applyMapCmd.Flags().StringVarP(&path, "path", "p", ".", "Directory the paths in the mapping are relative to")
+	applyMapCmd.Flags().BoolVarP(&skipHistory, "skip-history", "", false, "Skip adding a json file for operation history which can be used for undo")
+}
+
+func runApplyMap(cmd *cobra.Command, args []string) error {
+	if err := cli.ValidatePath(path); err != nil {
+		return err
+	}
+
+	pairs, err := mapping.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(pairs) == 0 {
+		return fmt.Errorf("mapping file %s contains no renames", args[0])
+	}
+
+	cfg := cli.Config{
+		Path:        path,
+		Mode:        "apply-map",
+		SkipHistory: skipHistory,
+		DryRun:      globalCfg.DryRun,
+	}
+
+	adapter := fs.NewAdapter()
+
+	ops := common.MapSlice(mapping.Resolve(pairs, cfg.Path), func(p mapping.Pair) engine.RenameOp {
+		return engine.RenameOp{OldPath: p.Old, NewPath: p.New}
+	})
+
+	planResult := engine.NewEngine(nil, adapter).PlanRenames(ops)
+
+	return executePlan(cfg, adapter, planResult)
+}
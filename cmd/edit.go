@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/MSmaili/renym/internal/cli"
+	"github.com/MSmaili/renym/internal/editor"
+	"github.com/MSmaili/renym/internal/engine"
+	"github.com/MSmaili/renym/internal/fs"
+	"github.com/MSmaili/renym/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Rename files by editing their names in $EDITOR",
+	Long: `Open the list of paths in $EDITOR and rename every line you change.
+
+Each line holds one path. Edit the names you want to change, keep the
+number and order of lines intact, then save and quit the editor.
+The renames go through the same collision checks, history and undo as
+the rename modes.`,
+	RunE: runEdit,
+	Example: `  # Edit the names in the current directory
+  renym edit
+
+  # Edit names recursively, including directories
+  renym edit -r -d -p ./photos
+
+  # Preview what the edit would rename
+  renym edit --dry-run`,
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+	addTraversalFlags(editCmd)
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	if err := cli.ValidatePath(path); err != nil {
+		return err
+	}
+	if err := cli.ValidateDepth(minDepth, traversalMaxDepth(cmd)); err != nil {
+		return err
+	}
+
+	cfg := cli.Config{
+		Path:            path,
+		Mode:            "edit",
+		MaxDepth:        traversalMaxDepth(cmd),
+		MinDepth:        minDepth,
+		Directories:     directories || dirsOnly,
+		Files:           !dirsOnly,
+		Ignore:          ignore,
+		Include:         include,
+		Extensions:      extensions,
+		Match:           match,
+		NoDefaultIgnore: noDefaultIgnore,
+		GitIgnore:       gitIgnore,
+		SkipHistory:     skipHistory,
+		DryRun:          globalCfg.DryRun,
+	}
+
+	adapter := fs.NewAdapter()
+
+	paths, err := walkPaths(cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		log.Info("\n✓ No files to rename\n")
+		return nil
+	}
+
+	edited, err := editor.EditPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	ops := editor.Renames(paths, edited)
+	planResult := engine.NewEngine(nil, adapter).PlanRenames(ops)
+
+	return executePlan(cfg, adapter, planResult)
+}
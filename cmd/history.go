@@ -0,0 +1,398 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MSmaili/renym/internal/cli"
+	"github.com/MSmaili/renym/internal/fs"
+	"github.com/MSmaili/renym/internal/history"
+	"github.com/MSmaili/renym/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Inspect rename history",
+	Long:  `Inspect the rename history that undo relies on.`,
+	Example: `  # List the history of the current directory
+  renym history list
+
+  # Show every rename of one entry
+  renym history show 2026-10-17_120000.408151923
+
+  # Find out what a file was called before
+  renym history which ./photos/beach.jpg
+
+  # Remove entries older than 30 days
+  renym history prune --older-than 30d`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list [path]",
+	Short: "List the history entries of a directory",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runHistoryList,
+	Example: `  # List the history of the current directory
+  renym history list
+
+  # List the history of another directory
+  renym history list ./photos`,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details and renames of a history entry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+	Example: `  # Show an entry listed by 'renym history list'
+  renym history show 2026-10-17_120000.408151923`,
+}
+
+var historyWhichCmd = &cobra.Command{
+	Use:   "which <file>",
+	Short: "Show which operations renamed a file and its original name",
+	Long: `Search the history of every directory for the operations that renamed a
+file to its current name, and show its original name.
+
+Files moved to another directory since are found by their recorded device
+and file number, even if they were edited since.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistoryWhich,
+	Example: `  # Find out what a file was called before
+  renym history which ./photos/beach.jpg`,
+}
+
+var (
+	pruneKeep      int
+	pruneOlderThan string
+	pruneMaxSize   string
+	clearAll       bool
+)
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old history entries of every directory",
+	Long: `Apply the history retention settings to the history of every directory,
+including directories that no longer exist.
+
+The limits default to RENYM_HISTORY_LIMIT, RENYM_HISTORY_MAX_AGE and
+RENYM_HISTORY_MAX_SIZE, the flags override them for this run.`,
+	Args: cobra.NoArgs,
+	RunE: runHistoryPrune,
+	Example: `  # Apply the configured retention
+  renym history prune
+
+  # Keep at most 5 entries per directory, none older than 30 days
+  renym history prune --keep 5 --older-than 30d
+
+  # Keep the whole history below 10MB
+  renym history prune --max-size 10MB`,
+}
+
+var historyClearCmd = &cobra.Command{
+	Use:   "clear [path]",
+	Short: "Delete the history of a directory",
+	Long: `Delete every history and redo entry of a directory, or of every
+directory with --all. Cleared operations can no longer be undone.`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: validateHistoryClearFlags,
+	RunE:    runHistoryClear,
+	Example: `  # Clear the history of the current directory
+  renym history clear
+
+  # Clear the history of another directory
+  renym history clear ./photos
+
+  # Clear the history of every directory
+  renym history clear --all`,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyWhichCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+	historyCmd.AddCommand(historyClearCmd)
+
+	historyPruneCmd.Flags().IntVar(&pruneKeep, "keep", 0, "Number of entries to keep per directory, 0 for no limit")
+	historyPruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Remove entries older than this age, such as 30d or 12h")
+	historyPruneCmd.Flags().StringVar(&pruneMaxSize, "max-size", "", "Remove the oldest entries until the history is at most this size, such as 10MB")
+
+	historyClearCmd.Flags().BoolVar(&clearAll, "all", false, "Clear the history of every directory")
+}
+
+func newHistoryStore() (*history.GlobalStore, error) {
+	store, err := history.NewGlobalStore(fs.NewAdapter())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize history store: %w", err)
+	}
+	return store, nil
+}
+
+func runHistoryList(cmd *cobra.Command, args []string) error {
+	dirPath := "."
+	if len(args) > 0 {
+		dirPath = args[0]
+	}
+
+	store, err := newHistoryStore()
+	if err != nil {
+		return err
+	}
+
+	entries, err := store.List(dirPath)
+	if err != nil {
+		return err
+	}
+
+	redo, err := store.ListRedo(dirPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if len(redo) > 0 {
+			log.Info("\n%d undone operation(s) can be re-applied with 'renym redo'\n", len(redo))
+		}
+	}()
+
+	if len(entries) == 0 {
+		log.Info("No history found for %s\n", dirPath)
+		return nil
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tSTATUS\tRENAMED\tSKIPPED\tCOLLISIONS\tCOMMAND")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			entry.ID,
+			entry.Timestamp.Local().Format(time.DateTime),
+			entryStatus(entry),
+			len(entry.Operations),
+			len(entry.Skipped),
+			len(entry.Collisions),
+			entry.Command,
+		)
+	}
+	w.Flush()
+
+	log.Info("History for %s\n\n%s", entries[0].Path, sb.String())
+	return nil
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	store, err := newHistoryStore()
+	if err != nil {
+		return err
+	}
+
+	entry, err := store.Find(args[0])
+	if err != nil {
+		return err
+	}
+
+	printHistoryEntry(entry)
+	return nil
+}
+
+func runHistoryWhich(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	adapter := fs.NewAdapter()
+	store, err := history.NewGlobalStore(adapter)
+	if err != nil {
+		return fmt.Errorf("failed to initialize history store: %w", err)
+	}
+
+	// A file that no longer exists can still be looked up by its path
+	var identity *history.Identity
+	if id, err := fs.Identify(adapter, filePath); err == nil {
+		identity = mapFSIdentityToHistory(id)
+	}
+
+	matches, err := store.Which(filePath, identity)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no history found for %s", filePath)
+	}
+
+	if matches[0].ByIdentity {
+		log.Info("Found by file identity, the file was moved after it was renamed\n\n")
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tOLD NAME\tNEW NAME\tID\tCOMMAND")
+	for _, match := range matches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			match.Entry.Timestamp.Local().Format(time.DateTime),
+			filepath.Base(match.Operation.Old),
+			filepath.Base(match.Operation.New),
+			match.Entry.ID,
+			match.Entry.Command,
+		)
+	}
+	w.Flush()
+	log.Info("%s", sb.String())
+
+	original := matches[len(matches)-1].Operation.Old
+	log.Info("\nOriginal name: %s\n", filepath.Base(original))
+	log.Info("Original path: %s\n", original)
+	return nil
+}
+
+func runHistoryPrune(cmd *cobra.Command, args []string) error {
+	retention, err := pruneRetention(cmd)
+	if err != nil {
+		return err
+	}
+
+	store, err := newHistoryStore()
+	if err != nil {
+		return err
+	}
+
+	result, err := store.Prune(retention)
+	if err != nil {
+		return err
+	}
+
+	log.Info("✓ Removed %d history entries (%s)\n", result.Entries, formatBytes(result.Bytes))
+	return nil
+}
+
+// pruneRetention returns the configured retention, overridden by the flags
+// given on the command line.
+func pruneRetention(cmd *cobra.Command) (history.Retention, error) {
+	retention, err := history.RetentionFromEnv()
+	if err != nil {
+		return retention, err
+	}
+
+	if cmd.Flags().Changed("keep") {
+		if pruneKeep < 0 {
+			return retention, fmt.Errorf("--keep must be 0 or more")
+		}
+		retention.MaxEntries = pruneKeep
+	}
+	if cmd.Flags().Changed("older-than") {
+		if retention.MaxAge, err = history.ParseAge(pruneOlderThan); err != nil {
+			return retention, fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+	if cmd.Flags().Changed("max-size") {
+		if retention.MaxTotalSize, err = history.ParseSize(pruneMaxSize); err != nil {
+			return retention, fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+
+	return retention, nil
+}
+
+func validateHistoryClearFlags(cmd *cobra.Command, args []string) error {
+	if clearAll && len(args) > 0 {
+		return fmt.Errorf("give either a path or --all, not both")
+	}
+	if len(args) > 0 {
+		return cli.ValidatePath(args[0])
+	}
+	return nil
+}
+
+func runHistoryClear(cmd *cobra.Command, args []string) error {
+	store, err := newHistoryStore()
+	if err != nil {
+		return err
+	}
+
+	var result history.PruneResult
+	if clearAll {
+		result, err = store.ClearAll()
+	} else {
+		dirPath := "."
+		if len(args) > 0 {
+			dirPath = args[0]
+		}
+		result, err = store.Clear(dirPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("✓ Removed %d history entries (%s)\n", result.Entries, formatBytes(result.Bytes))
+	return nil
+}
+
+// formatBytes formats a size for humans, such as 1.5 KB.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size)
+	suffix := ""
+	for _, s := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		suffix = s
+		if value < unit {
+			break
+		}
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+func printHistoryEntry(entry *history.Entry) {
+	separator := strings.Repeat("=", 60)
+	thinSeparator := strings.Repeat("-", 60)
+
+	log.Info("%s\n", separator)
+	log.Info("  ID:          %s\n", entry.ID)
+	log.Info("  Time:        %s\n", entry.Timestamp.Local().Format(time.DateTime))
+	log.Info("  Status:      %s\n", entryStatus(entry))
+	log.Info("  Path:        %s\n", entry.Path)
+	log.Info("  Command:     %s\n", entry.Command)
+	log.Info("  Version:     %s\n", entry.Version)
+	log.Info("  Renamed:     %d\n", len(entry.Operations))
+	log.Info("  Skipped:     %d\n", len(entry.Skipped))
+	log.Info("  Collisions:  %d\n", len(entry.Collisions))
+	log.Info("%s\n", separator)
+
+	if len(entry.Operations) > 0 {
+		log.Info("\nRENAMES:\n%s\n", thinSeparator)
+		for _, op := range entry.Operations {
+			log.Info("  %s → %s\n", op.Old, op.New)
+		}
+	}
+
+	if len(entry.Skipped) > 0 {
+		log.Info("\nSKIPPED:\n%s\n", thinSeparator)
+		for _, skipped := range entry.Skipped {
+			log.Info("  %s (%s)\n", skipped.Path, skipped.Reason)
+		}
+	}
+
+	if len(entry.Collisions) > 0 {
+		log.Info("\nCOLLISIONS:\n%s\n", thinSeparator)
+		for _, collision := range entry.Collisions {
+			log.Info("  %s, %s → %s\n", collision.Source1, collision.Source2, collision.Target)
+		}
+	}
+
+	log.Info("\n")
+}
+
+// entryStatus reports entries recorded before statuses existed as applied.
+func entryStatus(entry *history.Entry) history.Status {
+	if entry.Status == "" {
+		return history.StatusApplied
+	}
+	return entry.Status
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/MSmaili/renym/internal/cli"
+	"github.com/MSmaili/renym/internal/journal"
 	"github.com/MSmaili/renym/internal/log"
 	"github.com/spf13/cobra"
 )
@@ -23,21 +24,36 @@ var rootCmd = &cobra.Command{
 	Long: `Rename files and directories using automatic naming patterns.
 
 Modes:
-  upper   → FILENAME
-  lower   → filename
-  pascal  → FileName
-  camel   → fileName
-  snake   → file_name
-  kebab   → file-name
-  title   → File Name`,
+  upper    → FILENAME
+  lower    → filename
+  pascal   → FileName
+  camel    → fileName
+  snake    → file_name
+  kebab    → file-name
+  title    → File Name
+  replace  → regex find-and-replace (--find, --replace)
+  truncate → shorten names, e.g. truncate:40
+  template → name built from a template (--template)
+
+Modes can be chained with commas and run in order as a single operation,
+e.g. -m "replace:^IMG_=,snake,truncate:40".`,
 	Example: `
   renym -m upper
   renym -m snake -p ./photos
   renym -m kebab --dry-run
+  renym -m replace --find '^IMG_' --replace ''
+  renym -m "replace:^IMG_=,snake,truncate:40"
+  renym -m template --template "{mtime:2006-01-02}_{name:snake}{ext}"
   renym -m snake -v          # verbose output
   renym -m snake -q          # quiet mode`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		return setupLogging()
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		if cmd != recoverCmd {
+			warnInterruptedRenames()
+		}
+		return nil
 	},
 	PreRunE: validateFlags,
 	RunE:    runRename,
@@ -82,6 +98,25 @@ func setupLogging() error {
 	return nil
 }
 
+// warnInterruptedRenames points at `renym recover` when an earlier batch was
+// interrupted and left files half renamed.
+func warnInterruptedRenames() {
+	store, err := journal.NewStore()
+	if err != nil {
+		return
+	}
+
+	journals, err := store.Interrupted()
+	if err != nil || len(journals) == 0 {
+		return
+	}
+	for _, j := range journals {
+		j.Close()
+	}
+
+	log.Warn("%d interrupted rename(s) found, run 'renym recover' to finish or roll back\n\n", len(journals))
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		log.Error("%v\n", err)
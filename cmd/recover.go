@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MSmaili/renym/internal/common"
+	"github.com/MSmaili/renym/internal/fs"
+	"github.com/MSmaili/renym/internal/history"
+	"github.com/MSmaili/renym/internal/journal"
+	"github.com/MSmaili/renym/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recoverFinish   bool
+	recoverRollback bool
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Finish or roll back an interrupted rename",
+	Long: `Finish or roll back rename batches that were interrupted, e.g. by Ctrl-C,
+a crash or a power loss.
+
+Every rename batch is journaled before it starts. If renym stops halfway,
+the journal tells which files were already renamed. recover shows each
+interrupted batch and asks whether to finish it or roll it back, unless
+--finish or --rollback is given.`,
+	RunE: runRecover,
+	Example: `  # Review interrupted renames and choose what to do
+  renym recover
+
+  # Complete every interrupted rename
+  renym recover --finish
+
+  # Restore the original names
+  renym recover --rollback --dry-run`,
+}
+
+func init() {
+	rootCmd.AddCommand(recoverCmd)
+
+	recoverCmd.Flags().BoolVar(&recoverFinish, "finish", false, "Complete the interrupted renames")
+	recoverCmd.Flags().BoolVar(&recoverRollback, "rollback", false, "Restore the names from before the interrupted renames")
+	recoverCmd.MarkFlagsMutuallyExclusive("finish", "rollback")
+}
+
+func runRecover(cmd *cobra.Command, args []string) error {
+	store, err := journal.NewStore()
+	if err != nil {
+		return fmt.Errorf("failed to initialize journal store: %w", err)
+	}
+
+	journals, err := store.Interrupted()
+	if err != nil {
+		return err
+	}
+
+	if len(journals) == 0 {
+		log.Info("✓ No interrupted renames found\n")
+		return nil
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	for i, j := range journals {
+		if err := recoverJournal(j, stdin); err != nil {
+			for _, rest := range journals[i+1:] {
+				rest.Close()
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func recoverJournal(j *journal.Journal, stdin *bufio.Reader) error {
+	finishOps := j.FinishOps()
+
+	log.Info("Interrupted rename from %s\n", j.Batch.Time.Format(time.DateTime))
+	log.Info("  Command: %s\n", j.Batch.Command)
+	log.Info("  Path:    %s\n", j.Batch.Path)
+	log.Info("  Renamed: %d of %d\n", len(j.Batch.Ops)-len(finishOps), len(j.Batch.Ops))
+
+	finish, rollback := recoverFinish, recoverRollback
+	if !finish && !rollback {
+		finish, rollback = promptRecoverAction(stdin)
+	}
+
+	var ops []journal.Op
+	switch {
+	case finish:
+		ops = finishOps
+	case rollback:
+		ops = j.RollbackOps()
+	default:
+		log.Info("Skipped, the journal is kept for a later recover\n\n")
+		return j.Close()
+	}
+
+	adapter := fs.NewAdapter()
+	opts := fs.ApplyOptions{
+		DryRun:        globalCfg.DryRun,
+		CaseSensitive: adapter.IsCaseSensitive(),
+	}
+	if !opts.DryRun {
+		// Recovery is journaled too, in case it gets interrupted itself
+		opts.Journal = j
+	}
+
+	if err := fs.Apply(mapJournalToFS(ops), opts); err != nil {
+		j.Close()
+		return fmt.Errorf("recover failed: %w", err)
+	}
+
+	if opts.DryRun {
+		log.Info("Dry run, the journal is kept\n\n")
+		return j.Close()
+	}
+
+	if j.Batch.HistoryID != "" {
+		finishRecoveredHistory(adapter, j.Batch, finish)
+	}
+
+	if finish {
+		log.Info("✓ Finished %d remaining rename(s)\n\n", len(ops))
+	} else {
+		log.Info("✓ Rolled back %d rename(s)\n\n", len(ops))
+	}
+
+	return j.Remove()
+}
+
+func promptRecoverAction(stdin *bufio.Reader) (finish, rollback bool) {
+	log.Print("Finish (f), roll back (r) or skip (s)? ")
+
+	answer, _ := stdin.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "f", "finish":
+		return true, false
+	case "r", "rollback":
+		return false, true
+	}
+	return false, false
+}
+
+// finishRecoveredHistory records the outcome of a recovered batch in the
+// history entry the interrupted command saved as planned. A finished batch
+// can then be undone like any other.
+func finishRecoveredHistory(adapter fs.FileSystemAdapter, batch journal.Batch, finished bool) {
+	store, err := history.NewGlobalStore(adapter)
+	if err != nil {
+		log.Warn("history disabled: %v\n", err)
+		return
+	}
+
+	entries, err := store.List(batch.Path)
+	if err != nil {
+		log.Warn("could not update history: %v\n", err)
+		return
+	}
+	i := slices.IndexFunc(entries, func(e *history.Entry) bool { return e.ID == batch.HistoryID })
+	if i < 0 {
+		log.Warn("could not update history: entry '%s' not found\n", batch.HistoryID)
+		return
+	}
+
+	entry := entries[i]
+	entry.Operations = common.MapSlice(batch.Ops, func(op journal.Op) history.Operation {
+		return history.Operation{Old: op.Old, New: op.New}
+	})
+
+	status := history.StatusFailed
+	if finished {
+		status = history.StatusApplied
+		entry.Operations = withIdentities(adapter, entry.Operations)
+	}
+
+	if err := store.Finish(entry, status); err != nil {
+		log.Warn("could not update history: %v\n", err)
+	}
+}
+
+func mapJournalToFS(ops []journal.Op) []fs.RenameOp {
+	return common.MapSlice(ops, func(op journal.Op) fs.RenameOp {
+		return fs.RenameOp{
+			OldPath: op.Old,
+			NewPath: op.New,
+		}
+	})
+}
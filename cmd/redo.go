@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MSmaili/renym/internal/cli"
+	"github.com/MSmaili/renym/internal/common"
+	"github.com/MSmaili/renym/internal/fs"
+	"github.com/MSmaili/renym/internal/history"
+	"github.com/MSmaili/renym/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var redoPath string
+
+var redoCmd = &cobra.Command{
+	Use:   "redo [path]",
+	Short: "Redo undone rename operations",
+	Long: `Re-apply the rename operation that was most recently undone.
+
+Undone operations are kept on a redo stack per directory until a new
+rename runs in that directory. Redo checks that the files are still
+where undo left them before renaming anything.`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: validateRedoFlags,
+	RunE:    runRedo,
+	Example: `  # Redo the last undo in the current directory
+  renym redo
+
+  # Redo the last undo in another directory
+  renym redo ./photos`,
+}
+
+func init() {
+	rootCmd.AddCommand(redoCmd)
+
+	redoCmd.Flags().StringVarP(&redoPath, "path", "p", ".", "Directory to redo in")
+}
+
+func validateRedoFlags(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		if cmd.Flags().Changed("path") {
+			return fmt.Errorf("give the path either as an argument or with --path, not both")
+		}
+		redoPath = args[0]
+	}
+	return cli.ValidatePath(redoPath)
+}
+
+func runRedo(cmd *cobra.Command, args []string) error {
+	dryRun := globalCfg.DryRun
+
+	adapter := fs.NewAdapter()
+	store, err := history.NewGlobalStore(adapter)
+	if err != nil {
+		return fmt.Errorf("failed to initialize history store: %w", err)
+	}
+
+	entries, err := store.ListRedo(redoPath)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("nothing to redo for %s", redoPath)
+	}
+
+	entry := entries[0]
+	log.Info("Redoing %s: %s\n", entry.Timestamp.Local().Format(time.DateTime), entry.Command)
+
+	ops := mapHistoryToFs(entry)
+	if origin, err := store.Origin(entry); err != nil {
+		return err
+	} else if origin != nil {
+		// Part of the entry was never undone, its directories are still renamed
+		ops = relocateOps(ops, origin.Operations)
+	}
+
+	if err := applyHistoryOps(ops, adapter, entry.Path, dryRun); err != nil {
+		return err
+	}
+
+	separator := strings.Repeat("=", 60)
+	log.Info("%s\n", separator)
+	log.Info("  ✓ REDO COMPLETED SUCCESSFULLY\n")
+	log.Info("%s\n", separator)
+
+	if dryRun {
+		log.Info("We would have moved the entry back to history\n")
+		return nil
+	}
+
+	if err := store.Restore(entry); err != nil {
+		return err
+	}
+	log.Info("We moved the entry back to history\n")
+
+	return nil
+}
+
+// mapHistoryToFs returns the recorded renames in the order they originally
+// ran, deepest paths first.
+func mapHistoryToFs(entry *history.Entry) []fs.RenameOp {
+	ops := mapHistoryToFsInOrder(entry.Operations)
+	slices.Reverse(ops)
+	return ops
+}
+
+// mapHistoryToFsInOrder maps recorded renames in the order they are stored.
+// A rename keeps the file's identity, so the identity recorded for New also
+// identifies Old once the rename was undone.
+func mapHistoryToFsInOrder(ops []history.Operation) []fs.RenameOp {
+	return common.MapSlice(ops, func(e history.Operation) fs.RenameOp {
+		return fs.RenameOp{
+			OldPath:  e.Old,
+			NewPath:  e.New,
+			Identity: mapHistoryIdentityToFs(e.Identity),
+		}
+	})
+}
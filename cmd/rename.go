@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,7 +13,9 @@ import (
 	"github.com/MSmaili/renym/internal/engine"
 	"github.com/MSmaili/renym/internal/fs"
 	"github.com/MSmaili/renym/internal/history"
+	"github.com/MSmaili/renym/internal/journal"
 	"github.com/MSmaili/renym/internal/log"
+	"github.com/MSmaili/renym/internal/metadata"
 	"github.com/MSmaili/renym/internal/version"
 	"github.com/MSmaili/renym/internal/walker"
 	"github.com/spf13/cobra"
@@ -20,41 +23,53 @@ import (
 
 var (
 	mode            string
+	find            string
+	replaceWith     string
+	template        string
+	numberStart     int
+	numberPerDir    bool
+	sortOrder       string
 	path            string
 	recursive       bool
+	maxDepth        int
+	minDepth        int
 	directories     bool
 	dirsOnly        bool
 	ignore          []string
+	include         []string
+	extensions      []string
+	match           string
 	noDefaultIgnore bool
+	gitIgnore       bool
 	skipHistory     bool
 	showVersion     bool
 )
 
 func init() {
-	// Path flags
-	rootCmd.Flags().StringVarP(&path, "path", "p", ".", "Path to directory or file")
-
-	// Traversal flags
-	rootCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Recursively rename in subdirectories")
-
-	// dirs flags
-	rootCmd.Flags().BoolVarP(&directories, "directories", "d", false, "Include directories in rename (default = false)")
-	rootCmd.Flags().BoolVarP(&dirsOnly, "dirs-only", "D", false, "Rename only directories, skip files (default = false)")
-
-	// Filter flags
-	rootCmd.Flags().StringSliceVar(&ignore, "ignore", nil, "Glob pattern to ignore (can be specified multiple times)")
-	rootCmd.Flags().BoolVar(&noDefaultIgnore, "no-default-ignore", false, "Disable default ignore patterns (.git, .svn, .hg)")
-
-	// Backup
-	rootCmd.Flags().BoolVarP(&skipHistory, "skip-history", "", false, "Skip adding a json file for operation history which can be used for undo")
+	addTraversalFlags(rootCmd)
 
 	// Version
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "V", false, "Show the current installed version")
 
 	// Modes  flags
-	rootCmd.Flags().StringVarP(&mode, "mode", "m", "", "Rename mode: upper, lower, pascal, camel, snake, kebab, title")
+	rootCmd.Flags().StringVarP(&mode, "mode", "m", "", "Rename mode: upper, lower, pascal, camel, snake, kebab, title, replace, truncate, template; chain with commas")
 	rootCmd.RegisterFlagCompletionFunc("mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"upper", "lower", "pascal", "camel", "snake", "kebab", "title"}, cobra.ShellCompDirectiveNoFileComp
+		return []string{"upper", "lower", "pascal", "camel", "snake", "kebab", "title", "replace", "truncate", "template"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	// Replace mode flags
+	rootCmd.Flags().StringVar(&find, "find", "", "Regular expression to search for in names (replace mode)")
+	rootCmd.Flags().StringVar(&replaceWith, "replace", "", "Replacement text, supports $1 and ${name} capture references (replace mode)")
+
+	// Template mode flags
+	rootCmd.Flags().StringVar(&template, "template", "", "Name template, e.g. \"{mtime:2006-01-02}_{name:snake}{ext}\" (template mode)")
+	rootCmd.Flags().IntVar(&numberStart, "number-start", 1, "First value of the {n} sequence number (template mode)")
+	rootCmd.Flags().BoolVar(&numberPerDir, "number-per-dir", false, "Restart the {n} sequence in every directory (template mode)")
+
+	// Ordering flags
+	rootCmd.Flags().StringVar(&sortOrder, "sort", "", "Order files before renaming: name, natural, mtime, size")
+	rootCmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return cli.ValidSortOrders, cobra.ShellCompDirectiveNoFileComp
 	})
 
 	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
@@ -62,7 +77,7 @@ func init() {
 
 		if strings.Contains(msg, "flag needs an argument") && (strings.HasSuffix(msg, "-m") || strings.HasSuffix(msg, "--mode")) {
 			log.Error("The --mode flag requires a value.\n")
-			log.Error("Available modes: upper, lower, pascal, camel, snake, kebab, title\n")
+			log.Error("Available modes: upper, lower, pascal, camel, snake, kebab, title, replace, truncate, template\n")
 			log.Error("\nRun renym --help for more info\n")
 			os.Exit(1)
 		}
@@ -71,6 +86,45 @@ func init() {
 	})
 }
 
+// addTraversalFlags registers the flags that select which paths get renamed,
+// shared by every command that walks a directory.
+func addTraversalFlags(cmd *cobra.Command) {
+	// Path flags
+	cmd.Flags().StringVarP(&path, "path", "p", ".", "Path to directory or file")
+
+	// Traversal flags
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Recursively rename in subdirectories")
+	cmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Descend at most N directory levels below the path (default 1 without -r, 0 = unlimited)")
+	cmd.Flags().IntVar(&minDepth, "min-depth", 0, "Skip entries less than N directory levels below the path (default 0 = no minimum)")
+
+	// dirs flags
+	cmd.Flags().BoolVarP(&directories, "directories", "d", false, "Include directories in rename (default = false)")
+	cmd.Flags().BoolVarP(&dirsOnly, "dirs-only", "D", false, "Rename only directories, skip files (default = false)")
+
+	// Filter flags
+	cmd.Flags().StringSliceVar(&ignore, "ignore", nil, "Glob pattern to ignore, such as *.tmp or assets/**/raw (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&include, "include", nil, "Glob pattern of the paths to rename, such as docs/**/*.md (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&extensions, "ext", nil, "Rename only files with these extensions, such as jpg,png")
+	cmd.Flags().StringVar(&match, "match", "", "Rename only files and directories whose name matches this regex")
+	cmd.Flags().BoolVar(&noDefaultIgnore, "no-default-ignore", false, "Disable default ignore patterns (.git, .svn, .hg)")
+	cmd.Flags().BoolVar(&gitIgnore, "gitignore", false, "Skip paths ignored by git (.gitignore, .git/info/exclude, global excludes)")
+
+	// Backup
+	cmd.Flags().BoolVarP(&skipHistory, "skip-history", "", false, "Skip adding a json file for operation history which can be used for undo")
+}
+
+// traversalMaxDepth returns --max-depth when given, otherwise no limit with
+// -r and only the entries of the path itself without.
+func traversalMaxDepth(cmd *cobra.Command) int {
+	if cmd.Flags().Changed("max-depth") {
+		return maxDepth
+	}
+	if recursive {
+		return 0
+	}
+	return 1
+}
+
 func validateFlags(cmd *cobra.Command, args []string) error {
 	if showVersion {
 		log.Print("renym version %s\n", version.Version)
@@ -80,6 +134,12 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		_ = cmd.Help()
 		os.Exit(0)
 	}
+	if err := cli.ValidateSortOrder(sortOrder); err != nil {
+		return err
+	}
+	if err := cli.ValidateDepth(minDepth, traversalMaxDepth(cmd)); err != nil {
+		return err
+	}
 	return cli.ValidateFlags(mode, path)
 }
 
@@ -87,63 +147,88 @@ func runRename(cmd *cobra.Command, args []string) error {
 	cfg := cli.Config{
 		Path:            path,
 		Mode:            mode,
-		Recursive:       recursive,
+		Find:            find,
+		Replace:         replaceWith,
+		Template:        template,
+		NumberStart:     numberStart,
+		NumberPerDir:    numberPerDir,
+		Sort:            sortOrder,
+		MaxDepth:        traversalMaxDepth(cmd),
+		MinDepth:        minDepth,
 		Directories:     directories || dirsOnly,
 		Files:           !dirsOnly,
 		Ignore:          ignore,
+		Include:         include,
+		Extensions:      extensions,
+		Match:           match,
 		NoDefaultIgnore: noDefaultIgnore,
+		GitIgnore:       gitIgnore,
 		SkipHistory:     skipHistory,
 		DryRun:          globalCfg.DryRun,
 	}
 
-	adapter := fs.NewAdapter()
+	metadataProvider := metadata.NewMetadataProvider()
 
-	pathsToRename, err := walker.Walk(walker.Config{
-		Path:            cfg.Path,
-		Recursive:       cfg.Recursive,
-		Directories:     cfg.Directories,
-		NoDefaultIgnore: cfg.NoDefaultIgnore,
-		Files:           cfg.Files,
-		Ignore:          cfg.Ignore,
+	renameMode, err := engine.NewModeFromSpec(cfg.Mode, engine.ModeOptions{
+		Find:         cfg.Find,
+		Replace:      cfg.Replace,
+		Template:     cfg.Template,
+		NumberStart:  cfg.NumberStart,
+		NumberPerDir: cfg.NumberPerDir,
+		Metadata:     metadataProvider,
 	})
 	if err != nil {
 		return err
 	}
 
-	renameMode := engine.ModeRegistry[cfg.Mode]
+	adapter := fs.NewAdapter()
+
+	pathsToRename, err := walkPaths(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Sort != "" {
+		pathsToRename, err = engine.SortPaths(pathsToRename, cfg.Sort, metadataProvider)
+		if err != nil {
+			return err
+		}
+	}
+
 	engine := engine.NewEngine(renameMode, adapter)
 
-	// Sort paths by depth (deepest first) for safe recursive directory renames
+	// Names are computed in the --sort order, so it decides the numbering
+	planResult := engine.Plan(pathsToRename)
+
+	// Sort renames by depth (deepest first) for safe recursive directory renames
 	// Only needed when renaming directories to avoid parent path invalidation
 	if cfg.Directories {
-		pathsToRename = engine.SortPathsByDepth(pathsToRename)
+		planResult.Operations = engine.SortOpsByDepth(planResult.Operations)
 	}
 
-	planResult := engine.Plan(pathsToRename)
+	return executePlan(cfg, adapter, planResult)
+}
 
-	if !cfg.SkipHistory {
-		store, err := history.NewGlobalStore(adapter)
-		if err != nil {
-			log.Warn("history disabled: %v\n", err)
-		} else {
-			command := strings.Join(os.Args, " ")
-
-			_, err = store.Save(cfg.Path, history.Entry{
-				Timestamp:  time.Now(),
-				Command:    command,
-				Version:    version.Version,
-				Config:     cfg,
-				Operations: mapEngineOperationToHistory(planResult.Operations),
-				Skipped:    mapEngineSkippedFilesToHistory(planResult.Skipped),
-				Collisions: mapEngineCollosionToHistory(planResult.Collisions),
-			})
-
-			if err != nil {
-				log.Warn("could not save history: %v\n", err)
-			}
-		}
-	}
+func walkPaths(cfg cli.Config) ([]string, error) {
+	return walker.Walk(walker.Config{
+		Path:            cfg.Path,
+		MaxDepth:        cfg.MaxDepth,
+		MinDepth:        cfg.MinDepth,
+		Directories:     cfg.Directories,
+		NoDefaultIgnore: cfg.NoDefaultIgnore,
+		Files:           cfg.Files,
+		Ignore:          cfg.Ignore,
+		Include:         cfg.Include,
+		Extensions:      cfg.Extensions,
+		Match:           cfg.Match,
+		GitIgnore:       cfg.GitIgnore,
+	})
+}
 
+// executePlan applies the plan and records it in history. The entry is saved
+// as planned before any rename happens and updated with the outcome, only
+// applied entries can be undone. A dry run records nothing.
+func executePlan(cfg cli.Config, adapter fs.FileSystemAdapter, planResult engine.PlanResult) error {
 	if len(planResult.Operations) == 0 {
 		log.Info("\n✓ No files to rename\n")
 		return nil
@@ -151,7 +236,21 @@ func runRename(cmd *cobra.Command, args []string) error {
 
 	log.Debug("Processing %d file(s)...\n", len(planResult.Operations))
 
-	err = fs.Apply(mapEngineToFS(planResult.Operations), cfg.DryRun)
+	var store *history.GlobalStore
+	var entry *history.Entry
+	if !cfg.SkipHistory && !cfg.DryRun {
+		store, entry = beginHistory(cfg, adapter, planResult)
+	}
+
+	var finish func(error)
+	if entry != nil {
+		finish = func(applyErr error) { finishHistory(store, adapter, entry, applyErr) }
+	}
+
+	err := applyJournaled(mapEngineToFS(planResult.Operations), fs.ApplyOptions{
+		DryRun:        cfg.DryRun,
+		CaseSensitive: adapter.IsCaseSensitive(),
+	}, cfg.Path, historyID(entry), finish)
 	if err != nil {
 		return fmt.Errorf("rename operation failed: %w", err)
 	}
@@ -161,6 +260,110 @@ func runRename(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// applyJournaled applies ops behind a write-ahead journal, so a batch that is
+// interrupted can be finished or rolled back with `renym recover`. finish, if
+// set, records the outcome before the journal is removed: a batch interrupted
+// before its history entry is finished is still found by recover.
+func applyJournaled(ops []fs.RenameOp, opts fs.ApplyOptions, targetPath, historyID string, finish func(error)) error {
+	if finish == nil {
+		finish = func(error) {}
+	}
+
+	if opts.DryRun {
+		return fs.Apply(ops, opts)
+	}
+
+	j, err := beginJournal(ops, targetPath, historyID)
+	if err != nil {
+		log.Warn("journal disabled, an interrupted rename cannot be recovered: %v\n", err)
+		err = fs.Apply(ops, opts)
+		finish(err)
+		return err
+	}
+
+	opts.Journal = j
+	err = fs.Apply(ops, opts)
+	finish(err)
+	if errors.Is(err, fs.ErrRollbackIncomplete) {
+		// Keep the journal so the renames left in place can be recovered
+		j.Close()
+		return fmt.Errorf("%w\nRun 'renym recover' to finish or roll back the remaining renames", err)
+	}
+
+	if removeErr := j.Remove(); removeErr != nil {
+		log.Warn("%v\n", removeErr)
+	}
+	return err
+}
+
+func beginJournal(ops []fs.RenameOp, targetPath, historyID string) (*journal.Journal, error) {
+	store, err := journal.NewStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Begin(journal.Batch{
+		Time:      time.Now(),
+		Command:   strings.Join(os.Args, " "),
+		Path:      targetPath,
+		HistoryID: historyID,
+		Ops:       mapFSToJournal(ops),
+	})
+}
+
+// beginHistory saves the plan as a planned history entry. It returns a nil
+// entry if history cannot be written, the rename still runs.
+func beginHistory(cfg cli.Config, adapter fs.FileSystemAdapter, planResult engine.PlanResult) (*history.GlobalStore, *history.Entry) {
+	store, err := history.NewGlobalStore(adapter)
+	if err != nil {
+		log.Warn("history disabled: %v\n", err)
+		return nil, nil
+	}
+
+	entry := &history.Entry{
+		Timestamp:  time.Now(),
+		Command:    strings.Join(os.Args, " "),
+		Version:    version.Version,
+		Config:     cfg,
+		Operations: mapEngineOperationToHistory(planResult.Operations),
+		Skipped:    mapEngineSkippedFilesToHistory(planResult.Skipped),
+		Collisions: mapEngineCollosionToHistory(planResult.Collisions),
+	}
+	if err := store.Begin(cfg.Path, entry); err != nil {
+		log.Warn("could not save history: %v\n", err)
+		return nil, nil
+	}
+	return store, entry
+}
+
+// finishHistory records the outcome of applying a planned entry. A batch that
+// could not be fully rolled back keeps the renames still in place, so they can
+// be undone.
+func finishHistory(store *history.GlobalStore, adapter fs.FileSystemAdapter, entry *history.Entry, applyErr error) {
+	status := history.StatusApplied
+	var rollbackErr *fs.RollbackError
+	switch {
+	case applyErr == nil:
+		entry.Operations = withIdentities(adapter, entry.Operations)
+	case errors.As(applyErr, &rollbackErr):
+		status = history.StatusPartial
+		entry.Operations = withIdentities(adapter, mapFSToHistory(rollbackErr.Remaining))
+	default:
+		status = history.StatusFailed
+	}
+
+	if err := store.Finish(entry, status); err != nil {
+		log.Warn("could not save history: %v\n", err)
+	}
+}
+
+func historyID(entry *history.Entry) string {
+	if entry == nil {
+		return ""
+	}
+	return entry.ID
+}
+
 func printResults(result engine.PlanResult, dryRun bool) {
 	separator := strings.Repeat("=", 60)
 	thinSeparator := strings.Repeat("-", 60)
@@ -211,19 +414,61 @@ func mapEngineToFS(ops []engine.RenameOp) []fs.RenameOp {
 	})
 }
 
+func mapFSToJournal(ops []fs.RenameOp) []journal.Op {
+	return common.MapSlice(ops, func(op fs.RenameOp) journal.Op {
+		return journal.Op{
+			Old: op.OldPath,
+			New: op.NewPath,
+		}
+	})
+}
+
+// History records absolute paths, so an entry can be undone from any directory.
 func mapEngineOperationToHistory(ops []engine.RenameOp) []history.Operation {
 	return common.MapSlice(ops, func(e engine.RenameOp) history.Operation {
 		return history.Operation{
-			Old: e.OldPath,
-			New: e.NewPath,
+			Old: absPath(e.OldPath),
+			New: absPath(e.NewPath),
+		}
+	})
+}
+
+func mapFSToHistory(ops []fs.RenameOp) []history.Operation {
+	return common.MapSlice(ops, func(op fs.RenameOp) history.Operation {
+		return history.Operation{
+			Old: absPath(op.OldPath),
+			New: absPath(op.NewPath),
 		}
 	})
 }
 
+// withIdentities records the identity of every renamed file as it is on disk
+// now, so undo and redo can tell whether it was replaced or edited since.
+func withIdentities(adapter fs.FileSystemAdapter, ops []history.Operation) []history.Operation {
+	index := fs.NewRenameIndex(mapHistoryToFsInOrder(ops))
+	for i := range ops {
+		identity, err := fs.Identify(adapter, index.FinalPath(i, ops[i].New))
+		if err != nil {
+			log.Debug("could not record identity of %s: %v\n", ops[i].New, err)
+			continue
+		}
+		ops[i].Identity = mapFSIdentityToHistory(identity)
+	}
+	return ops
+}
+
+func mapFSIdentityToHistory(identity fs.FileIdentity) *history.Identity {
+	return &history.Identity{
+		ID:      identity.ID,
+		Size:    identity.Size,
+		ModTime: identity.ModTime,
+	}
+}
+
 func mapEngineSkippedFilesToHistory(ops []engine.SkippedFile) []history.Skipped {
 	return common.MapSlice(ops, func(e engine.SkippedFile) history.Skipped {
 		return history.Skipped{
-			Path:   e.Path,
+			Path:   absPath(e.Path),
 			Reason: e.Reason,
 		}
 	})
@@ -232,9 +477,16 @@ func mapEngineSkippedFilesToHistory(ops []engine.SkippedFile) []history.Skipped
 func mapEngineCollosionToHistory(ops []engine.Collision) []history.Collision {
 	return common.MapSlice(ops, func(e engine.Collision) history.Collision {
 		return history.Collision{
-			Source1: e.Source1,
-			Source2: e.Source2,
-			Target:  e.Target,
+			Source1: absPath(e.Source1),
+			Source2: absPath(e.Source2),
+			Target:  absPath(e.Target),
 		}
 	})
 }
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
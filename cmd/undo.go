@@ -1,9 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/MSmaili/renym/internal/cli"
 	"github.com/MSmaili/renym/internal/common"
 	"github.com/MSmaili/renym/internal/fs"
 	"github.com/MSmaili/renym/internal/history"
@@ -11,18 +16,83 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	undoPath   string
+	undoID     string
+	undoSteps  int
+	undoFilter history.Filter
+)
+
+// errNoMatchingRenames is returned when --only and --exclude leave nothing to
+// undo in an entry.
+var errNoMatchingRenames = errors.New("no renames match the --only and --exclude patterns")
+
 var undoCmd = &cobra.Command{
-	Use:   "undo",
+	Use:   "undo [path]",
 	Short: "Undo rename operations",
-	Long:  `Undo rename operations from history.`,
-	RunE:  runUndo,
+	Long: `Undo rename operations from history.
+
+By default the most recent operation in the current directory is undone.
+Give a directory, as an argument or with --path, to undo there instead.
+Give a file to undo only the most recent rename that produced it.
+
+Use --steps to undo several operations in a row, newest first, or --id to
+undo a specific entry listed by 'renym history list'.
+
+Use --only and --exclude to undo just the renames whose old or new name
+matches a glob pattern. The other renames stay in history and can still be
+undone later.`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: validateUndoFlags,
+	RunE:    runUndo,
 	Example: `  # Undo most recent operation in current directory
   renym undo
+
+  # Undo the most recent operation in another directory
+  renym undo ./photos
+
+  # Undo the rename of a single file
+  renym undo ./photos/beach_day.jpg
+
+  # Undo the last three operations, newest first
+  renym undo --steps 3
+
+  # Undo a specific history entry
+  renym undo --id 2026-10-17_120000.408151923
+
+  # Undo only the renames of JPEG files, keep the rest
+  renym undo --only "*.jpg" --exclude "cover*"
   `,
 }
 
 func init() {
 	rootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().StringVarP(&undoPath, "path", "p", ".", "Directory or renamed file to undo")
+	undoCmd.Flags().StringVar(&undoID, "id", "", "Undo the history entry with this ID (see 'renym history list')")
+	undoCmd.Flags().IntVar(&undoSteps, "steps", 1, "Undo the last N operations, newest first")
+	undoCmd.Flags().StringSliceVar(&undoFilter.Only, "only", nil, "Undo only renames matching this glob pattern (can be specified multiple times)")
+	undoCmd.Flags().StringSliceVar(&undoFilter.Exclude, "exclude", nil, "Keep renames matching this glob pattern (can be specified multiple times)")
+	undoCmd.MarkFlagsMutuallyExclusive("id", "steps")
+}
+
+func validateUndoFlags(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		if cmd.Flags().Changed("path") {
+			return fmt.Errorf("give the path either as an argument or with --path, not both")
+		}
+		undoPath = args[0]
+	}
+	if undoSteps < 1 {
+		return fmt.Errorf("--steps must be at least 1")
+	}
+	if err := undoFilter.Validate(); err != nil {
+		return err
+	}
+	if undoID != "" {
+		return nil
+	}
+	return cli.ValidatePath(undoPath)
 }
 
 func runUndo(cmd *cobra.Command, args []string) error {
@@ -34,16 +104,121 @@ func runUndo(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize history store: %w", err)
 	}
 
-	dirPath := "."
+	if info, err := os.Stat(undoPath); err == nil && !info.IsDir() && undoID == "" {
+		if undoSteps > 1 {
+			return fmt.Errorf("--steps cannot be used when undoing a single file")
+		}
+		if !undoFilter.IsEmpty() {
+			return fmt.Errorf("--only and --exclude cannot be used when undoing a single file")
+		}
+		return undoFile(store, adapter, undoPath, dryRun)
+	}
 
-	entry, err := store.Latest(dirPath)
+	entries, err := undoTargets(store, undoPath)
 	if err != nil {
 		return err
 	}
 
-	err = fs.Apply(mapHistoryInReverseToFs(entry), dryRun)
+	undone := 0
+	for i, entry := range entries {
+		if len(entries) > 1 {
+			log.Info("Undoing %s (%d of %d): %s\n", entry.ID, i+1, len(entries), entry.Command)
+		}
+
+		err := undoEntry(store, adapter, entry, dryRun)
+		if errors.Is(err, errNoMatchingRenames) && len(entries) > 1 {
+			log.Info("Nothing to undo in %s: %v\n", entry.ID, err)
+			continue
+		}
+		if err != nil {
+			if undone > 0 {
+				return fmt.Errorf("%w (%d of %d operations were undone)", err, undone, len(entries))
+			}
+			return err
+		}
+		undone++
+	}
+
+	if undone == 0 {
+		return errNoMatchingRenames
+	}
+	return nil
+}
+
+// undoTargets returns the entries to undo, in the order they are undone.
+func undoTargets(store *history.GlobalStore, dirPath string) ([]*history.Entry, error) {
+	if undoID != "" {
+		entry, err := store.Find(undoID)
+		if err != nil {
+			return nil, err
+		}
+		if !entry.Undoable() {
+			return nil, fmt.Errorf("history entry '%s' is %s, there is nothing to undo", undoID, entry.Status)
+		}
+		return []*history.Entry{entry}, nil
+	}
+
+	all, err := store.List(dirPath)
 	if err != nil {
-		return fmt.Errorf("rename operation failed: %w", err)
+		return nil, err
+	}
+	entries := slices.DeleteFunc(all, func(e *history.Entry) bool { return !e.Undoable() })
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("No history found for directory")
+	}
+
+	if undoSteps > len(entries) {
+		return nil, fmt.Errorf("cannot undo %d operations, only %d recorded for this directory", undoSteps, len(entries))
+	}
+
+	return entries[:undoSteps], nil
+}
+
+// undoFile reverts only the most recent rename that produced filePath and
+// keeps the rest of its history entry.
+func undoFile(store *history.GlobalStore, adapter fs.FileSystemAdapter, filePath string, dryRun bool) error {
+	entry, index, err := store.FindByTarget(filePath)
+	if err != nil {
+		return err
+	}
+
+	op := entry.Operations[index]
+	log.Info("Undoing %s → %s from %s: %s\n", filepath.Base(op.Old), filepath.Base(op.New), entry.ID, entry.Command)
+
+	single := *entry
+	single.Operations = []history.Operation{op}
+	rest := slices.Delete(slices.Clone(entry.Operations), index, index+1)
+
+	ops := relocateOps(mapHistoryInReverseToFs(&single), rest)
+	if err := applyHistoryOps(ops, adapter, entry.Path, dryRun); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err := moveToRedo(store, entry, &single, rest); err != nil {
+		return err
+	}
+
+	log.Info("✓ Restored %s\n", op.Old)
+	return nil
+}
+
+func undoEntry(store *history.GlobalStore, adapter fs.FileSystemAdapter, entry *history.Entry, dryRun bool) error {
+	selected, rest := undoFilter.Split(entry)
+	if len(selected) == 0 {
+		return errNoMatchingRenames
+	}
+
+	reverted := *entry
+	reverted.Operations = selected
+
+	ops := relocateOps(mapHistoryInReverseToFs(&reverted), rest)
+	if err := applyHistoryOps(ops, adapter, entry.Path, dryRun); err != nil {
+		return err
 	}
 
 	separator := strings.Repeat("=", 60)
@@ -51,25 +226,87 @@ func runUndo(cmd *cobra.Command, args []string) error {
 	log.Info("  ✓ UNDO COMPLETED SUCCESSFULLY\n")
 	log.Info("%s\n", separator)
 
+	if len(rest) > 0 {
+		log.Info("Undid %d of %d renames, %d stay in history\n", len(selected), len(entry.Operations), len(rest))
+	}
+
 	if dryRun {
-		log.Info("We would have removed entry from history\n")
+		log.Info("We would have moved the undone renames to the redo history\n")
 		return nil
 	}
 
-	err = store.Delete(dirPath)
-	if err != nil {
+	if err := moveToRedo(store, entry, &reverted, rest); err != nil {
 		return err
 	}
-	log.Info("We removed the entry from history\n")
+	log.Info("We moved the undone renames to the redo history, run 'renym redo' to re-apply them\n")
 
 	return nil
 }
 
+// moveToRedo pushes the reverted part of entry onto the redo stack and keeps
+// the rest of its renames in history, deleting the entry once none are left.
+func moveToRedo(store *history.GlobalStore, entry, reverted *history.Entry, rest []history.Operation) error {
+	if err := store.PushRedo(reverted); err != nil {
+		log.Warn("could not save redo history: %v\n", err)
+	}
+
+	if len(rest) == 0 {
+		return store.DeleteEntry(entry)
+	}
+	entry.Operations = rest
+	return store.Update(entry)
+}
+
+// applyHistoryOps re-applies renames recorded in history, for undo or redo,
+// after checking that the files are still where history says they are.
+func applyHistoryOps(ops []fs.RenameOp, adapter fs.FileSystemAdapter, targetPath string, dryRun bool) error {
+	if err := fs.CheckDrift(adapter, ops); err != nil {
+		return err
+	}
+
+	err := applyJournaled(ops, fs.ApplyOptions{
+		DryRun:        dryRun,
+		CaseSensitive: adapter.IsCaseSensitive(),
+	}, targetPath, "", nil)
+	if err != nil {
+		return fmt.Errorf("rename operation failed: %w", err)
+	}
+	return nil
+}
+
+// relocateOps resolves the recorded paths of ops to where they are on disk
+// while kept, the other renames of the same entry, stay applied. A file
+// renamed inside a directory is recorded under the directory's old name.
+func relocateOps(ops []fs.RenameOp, kept []history.Operation) []fs.RenameOp {
+	if len(kept) == 0 {
+		return ops
+	}
+
+	applied := fs.NewRenameIndex(mapHistoryToFs(&history.Entry{Operations: kept}))
+	for i := range ops {
+		ops[i].OldPath = applied.Relocate(ops[i].OldPath)
+		ops[i].NewPath = applied.Relocate(ops[i].NewPath)
+	}
+	return ops
+}
+
 func mapHistoryInReverseToFs(entry *history.Entry) []fs.RenameOp {
 	return common.MapSlice(entry.Operations, func(e history.Operation) fs.RenameOp {
 		return fs.RenameOp{
-			OldPath: e.New,
-			NewPath: e.Old,
+			OldPath:  e.New,
+			NewPath:  e.Old,
+			Identity: mapHistoryIdentityToFs(e.Identity),
 		}
 	})
 }
+
+func mapHistoryIdentityToFs(identity *history.Identity) *fs.FileIdentity {
+	if identity == nil {
+		return nil
+	}
+	return &fs.FileIdentity{
+		ID:      identity.ID,
+		Size:    identity.Size,
+		ModTime: identity.ModTime,
+	}
+}
@@ -3,11 +3,22 @@ package cli
 type Config struct {
 	Path            string
 	Mode            string
-	Recursive       bool
+	Find            string
+	Replace         string
+	Template        string
+	NumberStart     int
+	NumberPerDir    bool
+	Sort            string
+	MaxDepth        int
+	MinDepth        int
 	Directories     bool
 	Files           bool
 	Ignore          []string
+	Include         []string
+	Extensions      []string
+	Match           string
 	NoDefaultIgnore bool
+	GitIgnore       bool
 	DryRun          bool
 	SkipHistory     bool
 }
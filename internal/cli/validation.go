@@ -6,18 +6,42 @@ import (
 	"os"
 	"slices"
 	"strings"
+
+	"github.com/MSmaili/renym/internal/engine"
 )
 
-var ValidModes = []string{"upper", "lower", "pascal", "camel", "snake", "kebab", "title", "screaming", "sentence"}
+var ValidSortOrders = []string{"name", "natural", "mtime", "size"}
 
 // ErrConflictingFlags is returned when mutually exclusive flags are used together
 var ErrConflictingFlags = errors.New("conflicting flags")
 
+// ValidateMode checks every step of a mode spec, which is either a single
+// mode or a comma separated pipeline such as "replace:^IMG_=,snake,truncate:40".
 func ValidateMode(mode string) error {
-	if slices.Contains(ValidModes, mode) {
+	return engine.ValidateModeSpec(mode)
+}
+
+// ValidateSortOrder checks the --sort value; an empty order keeps traversal order.
+func ValidateSortOrder(order string) error {
+	if order == "" || slices.Contains(ValidSortOrders, order) {
 		return nil
 	}
-	return fmt.Errorf("invalid mode '%s'. Valid modes are: %s", mode, strings.Join(ValidModes, ", "))
+	return fmt.Errorf("invalid sort order '%s'. Valid orders are: %s", order, strings.Join(ValidSortOrders, ", "))
+}
+
+// ValidateDepth checks the depth limits of a walk, where a maxDepth of 0
+// means no limit.
+func ValidateDepth(minDepth, maxDepth int) error {
+	if minDepth < 0 {
+		return fmt.Errorf("--min-depth must be 0 or more")
+	}
+	if maxDepth < 0 {
+		return fmt.Errorf("--max-depth must be 0 or more")
+	}
+	if maxDepth > 0 && minDepth > maxDepth {
+		return fmt.Errorf("--min-depth %d is deeper than the maximum depth %d, use -r or a larger --max-depth", minDepth, maxDepth)
+	}
+	return nil
 }
 
 func ValidatePath(path string) error {
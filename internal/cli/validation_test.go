@@ -20,6 +20,12 @@ func TestValidateMode(t *testing.T) {
 		{"valid_kebab", "kebab", false},
 		{"valid_title", "title", false},
 		{"valid_screaming", "screaming", false},
+		{"valid_replace", "replace", false},
+		{"valid_template", "template", false},
+		{"valid_truncate_with_arg", "truncate:40", false},
+		{"valid_pipeline", "replace:^IMG_=,snake,truncate:40", false},
+		{"invalid_step_in_pipeline", "snake,shout", true},
+		{"empty_step_in_pipeline", "snake,", true},
 		{"invalid_mode", "invalid", true},
 		{"empty_mode", "", true},
 		{"random_mode", "randomstring", true},
@@ -113,3 +119,56 @@ func TestValidateGlobalFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSortOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		order     string
+		expectErr bool
+	}{
+		{"empty_keeps_traversal_order", "", false},
+		{"name", "name", false},
+		{"natural", "natural", false},
+		{"mtime", "mtime", false},
+		{"size", "size", false},
+		{"invalid", "random", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSortOrder(tt.order)
+			if tt.expectErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		minDepth  int
+		maxDepth  int
+		expectErr bool
+	}{
+		{"defaults", 0, 1, false},
+		{"no_limit", 3, 0, false},
+		{"within_max", 2, 2, false},
+		{"min_beyond_max", 2, 1, true},
+		{"negative_min", -1, 0, true},
+		{"negative_max", 0, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDepth(tt.minDepth, tt.maxDepth)
+			if tt.expectErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/MSmaili/renym/internal/engine"
+)
+
+// Command returns the editor to launch, preferring $VISUAL over $EDITOR.
+func Command() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// EditPaths writes one path per line to a temporary file, opens it in the
+// user's editor and returns the edited paths, line for line.
+func EditPaths(paths []string) ([]string, error) {
+	tmp, err := os.CreateTemp("", "renym-edit-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strings.Join(paths, "\n") + "\n"); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := run(Command(), tmp.Name()); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited file: %w", err)
+	}
+
+	return parseEdited(paths, string(data))
+}
+
+// Renames pairs every path with its edited line and returns the ones that
+// changed. Edited lines are cleaned, so paths are compared cleaned as well.
+func Renames(paths, edited []string) []engine.RenameOp {
+	var ops []engine.RenameOp
+	for i, path := range paths {
+		path = filepath.Clean(path)
+		if edited[i] != path {
+			ops = append(ops, engine.RenameOp{OldPath: path, NewPath: edited[i]})
+		}
+	}
+	return ops
+}
+
+func run(command, file string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("no editor configured, set $EDITOR")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %w", command, err)
+	}
+	return nil
+}
+
+// parseEdited splits the edited content into lines and checks that every
+// original path still has exactly one line.
+func parseEdited(original []string, content string) ([]string, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.TrimRight(content, "\n")
+
+	var lines []string
+	if content != "" {
+		lines = strings.Split(content, "\n")
+	}
+
+	if len(lines) != len(original) {
+		return nil, fmt.Errorf("expected %d lines but found %d, lines must not be added or removed", len(original), len(lines))
+	}
+
+	edited := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			return nil, fmt.Errorf("line %d is empty", i+1)
+		}
+		edited[i] = filepath.Clean(line)
+	}
+
+	return edited, nil
+}
@@ -0,0 +1,105 @@
+package editor
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestParseEdited(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  []string
+		content   string
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "unchanged",
+			original: []string{"a.txt", "b.txt"},
+			content:  "a.txt\nb.txt\n",
+			expected: []string{"a.txt", "b.txt"},
+		},
+		{
+			name:     "renamed_lines",
+			original: []string{"a.txt", filepath.Join("sub", "b.txt")},
+			content:  "first.txt\n" + filepath.Join("sub", "second.txt") + "\n",
+			expected: []string{"first.txt", filepath.Join("sub", "second.txt")},
+		},
+		{
+			name:     "windows_line_endings",
+			original: []string{"a.txt", "b.txt"},
+			content:  "x.txt\r\ny.txt\r\n",
+			expected: []string{"x.txt", "y.txt"},
+		},
+		{
+			name:     "missing_trailing_newline",
+			original: []string{"a.txt"},
+			content:  "x.txt",
+			expected: []string{"x.txt"},
+		},
+		{
+			name:      "line_removed",
+			original:  []string{"a.txt", "b.txt"},
+			content:   "a.txt\n",
+			expectErr: true,
+		},
+		{
+			name:      "line_added",
+			original:  []string{"a.txt"},
+			content:   "a.txt\nb.txt\n",
+			expectErr: true,
+		},
+		{
+			name:      "blank_line",
+			original:  []string{"a.txt", "b.txt"},
+			content:   "a.txt\n \n",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEdited(tt.original, tt.content)
+			if tt.expectErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.SliceEqual(t, got, tt.expected)
+		})
+	}
+}
+
+func TestRenames(t *testing.T) {
+	paths := []string{"./a.txt", filepath.Join("sub", "b.txt"), "c.txt"}
+	edited := []string{"a.txt", filepath.Join("sub", "b.txt"), "d.txt"}
+
+	ops := Renames(paths, edited)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, ops[0].OldPath, "c.txt")
+	assert.Equal(t, ops[0].NewPath, "d.txt")
+}
+
+func TestEditPathsUnchanged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs an editor that exits without changes")
+	}
+	t.Setenv("VISUAL", "true")
+
+	paths := []string{"./a.txt", "./sub/../b.txt"}
+	edited, err := EditPaths(paths)
+	assert.Nil(t, err)
+	assert.Len(t, Renames(paths, edited), 0)
+}
+
+func TestCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "nano")
+	assert.Equal(t, Command(), "nano")
+
+	t.Setenv("VISUAL", "code --wait")
+	assert.Equal(t, Command(), "code --wait")
+}
@@ -1,17 +1,27 @@
 package engine
 
 type Counter struct {
-	global int
-	perDir map[string]int
+	global   int
+	dirStart int
+	perDir   map[string]int
 }
 
 func NewCounter(start int) *Counter {
 	return &Counter{
-		global: start,
-		perDir: make(map[string]int),
+		global:   start,
+		dirStart: 1,
+		perDir:   make(map[string]int),
 	}
 }
 
+// NewDirCounter returns a counter whose per-directory sequences begin at start
+// instead of 1.
+func NewDirCounter(start int) *Counter {
+	counter := NewCounter(start)
+	counter.dirStart = start
+	return counter
+}
+
 func (c *Counter) Next(dir string) int {
 	if len(dir) > 0 {
 		return c.nextForDir(dir)
@@ -24,7 +34,7 @@ func (c *Counter) Next(dir string) int {
 
 func (c *Counter) nextForDir(dir string) int {
 	if _, exists := c.perDir[dir]; !exists {
-		c.perDir[dir] = 1
+		c.perDir[dir] = c.dirStart
 	}
 	val := c.perDir[dir]
 	c.perDir[dir]++
@@ -255,3 +255,21 @@ func TestCounterEdgeCases(t *testing.T) {
 		assert.Equal(t, counter.Next("/path/with-dashes"), 2)
 	})
 }
+
+func TestDirCounter(t *testing.T) {
+	t.Run("per_dir_starts_at_given_value", func(t *testing.T) {
+		counter := NewDirCounter(10)
+
+		assert.Equal(t, counter.Next("/a"), 10)
+		assert.Equal(t, counter.Next("/a"), 11)
+		assert.Equal(t, counter.Next("/b"), 10)
+	})
+
+	t.Run("global_uses_same_start", func(t *testing.T) {
+		counter := NewDirCounter(0)
+
+		assert.Equal(t, counter.Next(""), 0)
+		assert.Equal(t, counter.Next("/a"), 0)
+		assert.Equal(t, counter.Next(""), 1)
+	})
+}
@@ -30,6 +30,7 @@ type RenameOp struct {
 }
 type FileSystemAdapter interface {
 	IsCaseSensitive() bool
+	IsValidName(name string) bool
 	SanitizeName(name string) string
 }
 
@@ -46,12 +47,58 @@ func NewEngine(mode RenameMode, adapter FileSystemAdapter) *Engine {
 }
 
 func (e *Engine) Plan(paths []string) PlanResult {
-	planResult := PlanResult{
-		Operations: []RenameOp{},
-		Skipped:    []SkippedFile{},
-		Collisions: []Collision{},
+	planResult := newPlanResult()
+
+	ops := make([]RenameOp, 0, len(paths))
+	for _, path := range paths {
+		newPath, err := e.computeNewPathPerSelectedMode(path)
+		if err != nil {
+			e.addSkipped(&planResult, path, err.Error())
+			continue
+		}
+
+		ops = append(ops, RenameOp{OldPath: path, NewPath: newPath})
+	}
+
+	e.resolve(&planResult, ops)
+
+	return planResult
+}
+
+// PlanRenames runs explicitly given renames, e.g. from an editor or a mapping
+// file, through the same checks as Plan. Operations are ordered deepest first
+// so renaming a directory never invalidates the paths of its children.
+func (e *Engine) PlanRenames(ops []RenameOp) PlanResult {
+	planResult := newPlanResult()
+
+	caseSensitive := e.adapter.IsCaseSensitive()
+	sources := make(map[string]bool, len(ops))
+
+	valid := make([]RenameOp, 0, len(ops))
+	for _, op := range ops {
+		if _, err := os.Lstat(op.OldPath); err != nil {
+			e.addSkipped(&planResult, op.OldPath, "source not found")
+			continue
+		}
+
+		key := compareKey(op.OldPath, caseSensitive)
+		if sources[key] {
+			e.addSkipped(&planResult, op.OldPath, "duplicate source in batch")
+			continue
+		}
+		sources[key] = true
+
+		valid = append(valid, op)
 	}
 
+	e.resolve(&planResult, e.SortOpsByDepth(valid))
+
+	return planResult
+}
+
+// resolve filters ops down to the renames that are safe to apply, recording
+// everything else as skipped or colliding.
+func (e *Engine) resolve(planResult *PlanResult, ops []RenameOp) {
 	caseSensitive := e.adapter.IsCaseSensitive()
 
 	type pendingOp struct {
@@ -61,14 +108,19 @@ func (e *Engine) Plan(paths []string) PlanResult {
 	}
 
 	var pending []pendingOp
-	beingRenamed := make(map[string]bool, len(paths))
+	beingRenamed := make(map[string]bool, len(ops))
 
-	for _, path := range paths {
-		newPath := e.computeNewPathPerSelectedMode(path)
+	for _, op := range ops {
+		path, newPath := op.OldPath, op.NewPath
 		newPathCompare := compareKey(newPath, caseSensitive)
 
 		if newPath == path {
-			e.addSkipped(&planResult, path, "no change")
+			e.addSkipped(planResult, path, "no change")
+			continue
+		}
+
+		if !e.isValidTarget(path, newPath) {
+			e.addSkipped(planResult, path, "invalid name")
 			continue
 		}
 
@@ -82,32 +134,66 @@ func (e *Engine) Plan(paths []string) PlanResult {
 	}
 
 	seen := make(map[string]string, len(pending))
+	accepted := make([]pendingOp, 0, len(pending))
 
 	for _, op := range pending {
-		if e.hasDiskCollision(op.newPath, op.newPathCompare, beingRenamed) {
-			e.addSkipped(&planResult, op.oldPath, "target already exists")
-			e.addCollision(&planResult, op.newPath, op.oldPath, op.newPath)
+		if e.hasDiskCollision(op.oldPath, op.newPath, op.newPathCompare, beingRenamed) {
+			e.addSkipped(planResult, op.oldPath, "target already exists")
+			e.addCollision(planResult, op.newPath, op.oldPath, op.newPath)
 			continue
 		}
 
 		if existingSource, exists := seen[op.newPathCompare]; exists {
-			e.addSkipped(&planResult, op.oldPath, "duplicate target in batch")
-			e.addCollision(&planResult, existingSource, op.oldPath, op.newPath)
+			e.addSkipped(planResult, op.oldPath, "duplicate target in batch")
+			e.addCollision(planResult, existingSource, op.oldPath, op.newPath)
 			continue
 		}
 
+		accepted = append(accepted, op)
+		seen[op.newPathCompare] = op.oldPath
+	}
+
+	// A target was only free because its current owner was going to be renamed
+	// away. If that rename got skipped, the target is taken after all, which may
+	// in turn block another rename, so repeat until nothing changes.
+	for changed := true; changed; {
+		changed = false
+
+		movingAway := make(map[string]bool, len(accepted))
+		for _, op := range accepted {
+			movingAway[compareKey(op.oldPath, caseSensitive)] = true
+		}
+
+		kept := accepted[:0]
+		for _, op := range accepted {
+			if e.hasDiskCollision(op.oldPath, op.newPath, op.newPathCompare, movingAway) {
+				e.addSkipped(planResult, op.oldPath, "target already exists")
+				e.addCollision(planResult, op.newPath, op.oldPath, op.newPath)
+				changed = true
+				continue
+			}
+			kept = append(kept, op)
+		}
+		accepted = kept
+	}
+
+	for _, op := range accepted {
 		planResult.Operations = append(planResult.Operations, RenameOp{
 			OldPath: op.oldPath,
 			NewPath: op.newPath,
 		})
-
-		seen[op.newPathCompare] = op.oldPath
 	}
+}
 
-	return planResult
+func newPlanResult() PlanResult {
+	return PlanResult{
+		Operations: []RenameOp{},
+		Skipped:    []SkippedFile{},
+		Collisions: []Collision{},
+	}
 }
 
-func (e *Engine) computeNewPathPerSelectedMode(path string) string {
+func (e *Engine) computeNewPathPerSelectedMode(path string) (string, error) {
 	dir := filepath.Dir(path)
 	oldName := filepath.Base(path)
 
@@ -115,11 +201,20 @@ func (e *Engine) computeNewPathPerSelectedMode(path string) string {
 	nameWithoutExt := strings.TrimSuffix(oldName, ext)
 
 	transformedName := e.adapter.SanitizeName(nameWithoutExt)
+
+	if fileMode, ok := e.mode.(FileRenameMode); ok {
+		newName, err := fileMode.TransformFile(path, transformedName)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, newName), nil
+	}
+
 	transformedName = e.mode.Transform(transformedName)
 
 	newName := transformedName + ext
 
-	return filepath.Join(dir, newName)
+	return filepath.Join(dir, newName), nil
 }
 
 // compareKey returns the comparison key for a path based on case sensitivity
@@ -130,12 +225,36 @@ func compareKey(path string, caseSensitive bool) string {
 	return path
 }
 
-// hasDiskCollision checks if the target path exists on disk and is not being renamed away
-func (e *Engine) hasDiskCollision(newPath, compareKey string, beingRenamed map[string]bool) bool {
-	if _, err := os.Stat(newPath); err == nil {
-		return !beingRenamed[compareKey]
+// isValidTarget reports whether newPath is a valid name in the same directory as path.
+// Modes such as replace can produce empty names or names containing separators,
+// which filepath.Join would silently turn into a different location.
+func (e *Engine) isValidTarget(path, newPath string) bool {
+	if filepath.Dir(newPath) != filepath.Dir(path) {
+		return false
+	}
+	return e.adapter.IsValidName(filepath.Base(newPath))
+}
+
+// hasDiskCollision checks if the target path exists on disk and is not being renamed away.
+// A target that is the source itself under a different case (Foo.txt → foo.txt on a
+// case-folding filesystem) is a self-rename, not a collision.
+func (e *Engine) hasDiskCollision(oldPath, newPath, compareKey string, beingRenamed map[string]bool) bool {
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return false
+	}
+	if beingRenamed[compareKey] {
+		return false
+	}
+	return !isSameFileCaseFolded(oldPath, newPath, newInfo)
+}
+
+func isSameFileCaseFolded(oldPath, newPath string, newInfo os.FileInfo) bool {
+	if !strings.EqualFold(oldPath, newPath) {
+		return false
 	}
-	return false
+	oldInfo, err := os.Stat(oldPath)
+	return err == nil && os.SameFile(oldInfo, newInfo)
 }
 
 // addSkipped adds a file to the skipped list
@@ -160,14 +279,15 @@ func pathDepth(path string) int {
 	return strings.Count(path, string(filepath.Separator))
 }
 
-// SortPathsByDepth sorts paths with deepest paths first to ensure
-// safe recursive directory renaming (children before parents)
-func (e *Engine) SortPathsByDepth(paths []string) []string {
-	sorted := make([]string, len(paths))
-	copy(sorted, paths)
+// SortOpsByDepth sorts ops with the deepest old paths first to ensure safe
+// recursive directory renaming (children before parents). Ops at the same
+// depth keep their order.
+func (e *Engine) SortOpsByDepth(ops []RenameOp) []RenameOp {
+	sorted := make([]RenameOp, len(ops))
+	copy(sorted, ops)
 
 	sort.SliceStable(sorted, func(i, j int) bool {
-		return pathDepth(sorted[i]) > pathDepth(sorted[j])
+		return pathDepth(sorted[i].OldPath) > pathDepth(sorted[j].OldPath)
 	})
 
 	return sorted
@@ -18,6 +18,10 @@ func (m *mockAdapter) IsCaseSensitive() bool {
 	return m.caseSensitive
 }
 
+func (m *mockAdapter) IsValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
 func (m *mockAdapter) SanitizeName(name string) string {
 	if m.sanitize != nil {
 		return m.sanitize(name)
@@ -233,6 +237,32 @@ func TestPlan(t *testing.T) {
 			expectedSkipped:   []SkippedFile{},
 			expectedCollCount: 0,
 		},
+		{
+			name:          "empty_name_skipped",
+			mode:          mockMode{transform: func(s string) string { return "" }},
+			caseSensitive: true,
+			inputPaths: []string{
+				filepath.Join(tempDir, "README"),
+			},
+			expectedOps: []RenameOp{},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "README"), Reason: "invalid name"},
+			},
+			expectedCollCount: 0,
+		},
+		{
+			name:          "name_with_separator_skipped",
+			mode:          mockMode{transform: func(s string) string { return "../escape" }},
+			caseSensitive: true,
+			inputPaths: []string{
+				filepath.Join(tempDir, "foo.txt"),
+			},
+			expectedOps: []RenameOp{},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "foo.txt"), Reason: "invalid name"},
+			},
+			expectedCollCount: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -271,6 +301,158 @@ func TestPlan(t *testing.T) {
 	}
 }
 
+func TestPlanRenames(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name              string
+		existingFiles     []string
+		ops               []RenameOp
+		expectedOps       []RenameOp
+		expectedSkipped   []SkippedFile
+		expectedCollCount int
+	}{
+		{
+			name:          "explicit_rename",
+			existingFiles: []string{"a.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+			},
+			expectedOps: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+			},
+			expectedSkipped: []SkippedFile{},
+		},
+		{
+			name: "missing_source_skipped",
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "missing.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+			},
+			expectedOps: []RenameOp{},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "missing.txt"), Reason: "source not found"},
+			},
+		},
+		{
+			name:          "duplicate_source_skipped",
+			existingFiles: []string{"a.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "c.txt")},
+			},
+			expectedOps: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+			},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "a.txt"), Reason: "duplicate source in batch"},
+			},
+		},
+		{
+			name:          "unchanged_and_disk_collision",
+			existingFiles: []string{"a.txt", "b.txt", "taken.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "a.txt")},
+				{OldPath: filepath.Join(tempDir, "b.txt"), NewPath: filepath.Join(tempDir, "taken.txt")},
+			},
+			expectedOps: []RenameOp{},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "a.txt"), Reason: "no change"},
+				{Path: filepath.Join(tempDir, "b.txt"), Reason: "target already exists"},
+			},
+			expectedCollCount: 1,
+		},
+		{
+			name:          "children_before_parents",
+			existingFiles: []string{"dir/file.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "dir"), NewPath: filepath.Join(tempDir, "folder")},
+				{OldPath: filepath.Join(tempDir, "dir", "file.txt"), NewPath: filepath.Join(tempDir, "dir", "renamed.txt")},
+			},
+			expectedOps: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "dir", "file.txt"), NewPath: filepath.Join(tempDir, "dir", "renamed.txt")},
+				{OldPath: filepath.Join(tempDir, "dir"), NewPath: filepath.Join(tempDir, "folder")},
+			},
+			expectedSkipped: []SkippedFile{},
+		},
+		{
+			name:          "swap_is_planned",
+			existingFiles: []string{"a.txt", "b.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+				{OldPath: filepath.Join(tempDir, "b.txt"), NewPath: filepath.Join(tempDir, "a.txt")},
+			},
+			expectedOps: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+				{OldPath: filepath.Join(tempDir, "b.txt"), NewPath: filepath.Join(tempDir, "a.txt")},
+			},
+			expectedSkipped: []SkippedFile{},
+		},
+		{
+			name:          "target_stays_taken_when_its_rename_is_skipped",
+			existingFiles: []string{"a.txt", "b.txt", "c.txt", "taken.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "b.txt")},
+				{OldPath: filepath.Join(tempDir, "b.txt"), NewPath: filepath.Join(tempDir, "c.txt")},
+				{OldPath: filepath.Join(tempDir, "c.txt"), NewPath: filepath.Join(tempDir, "taken.txt")},
+			},
+			expectedOps: []RenameOp{},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "c.txt"), Reason: "target already exists"},
+				{Path: filepath.Join(tempDir, "b.txt"), Reason: "target already exists"},
+				{Path: filepath.Join(tempDir, "a.txt"), Reason: "target already exists"},
+			},
+			expectedCollCount: 3,
+		},
+		{
+			name:          "move_to_other_directory_is_invalid",
+			existingFiles: []string{"a.txt"},
+			ops: []RenameOp{
+				{OldPath: filepath.Join(tempDir, "a.txt"), NewPath: filepath.Join(tempDir, "other", "a.txt")},
+			},
+			expectedOps: []RenameOp{},
+			expectedSkipped: []SkippedFile{
+				{Path: filepath.Join(tempDir, "a.txt"), Reason: "invalid name"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, file := range tt.existingFiles {
+				path := filepath.Join(tempDir, file)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+					t.Fatalf("failed to create test file %s: %v", path, err)
+				}
+				defer os.RemoveAll(path)
+			}
+
+			engine := NewEngine(nil, &mockAdapter{caseSensitive: true})
+			result := engine.PlanRenames(tt.ops)
+
+			assert.Len(t, result.Operations, len(tt.expectedOps))
+			for i, expectedOp := range tt.expectedOps {
+				if i < len(result.Operations) {
+					assert.Equal(t, result.Operations[i].OldPath, expectedOp.OldPath)
+					assert.Equal(t, result.Operations[i].NewPath, expectedOp.NewPath)
+				}
+			}
+
+			assert.Len(t, result.Skipped, len(tt.expectedSkipped))
+			for i, expectedSkipped := range tt.expectedSkipped {
+				if i < len(result.Skipped) {
+					assert.Equal(t, result.Skipped[i].Path, expectedSkipped.Path)
+					assert.Equal(t, result.Skipped[i].Reason, expectedSkipped.Reason)
+				}
+			}
+
+			assert.Len(t, result.Collisions, tt.expectedCollCount)
+		})
+	}
+}
+
 func TestComputeNewPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -341,7 +523,8 @@ func TestComputeNewPath(t *testing.T) {
 			adapter := &mockAdapter{caseSensitive: true, sanitize: tt.sanitize}
 			engine := NewEngine(tt.mode, adapter)
 
-			result := engine.computeNewPathPerSelectedMode(tt.input)
+			result, err := engine.computeNewPathPerSelectedMode(tt.input)
+			assert.Nil(t, err)
 			assert.Equal(t, result, tt.expected)
 		})
 	}
@@ -393,8 +576,10 @@ func TestHasDiskCollision(t *testing.T) {
 
 	tests := []struct {
 		name          string
+		oldPath       string
 		newPath       string
 		createFile    bool
+		linkOldPath   bool
 		beingRenamed  map[string]bool
 		caseSensitive bool
 		expected      bool
@@ -417,6 +602,26 @@ func TestHasDiskCollision(t *testing.T) {
 			caseSensitive: true,
 			expected:      false,
 		},
+		{
+			name:          "same_file_under_other_case_is_self_rename",
+			oldPath:       filepath.Join(tempDir, "Folded.txt"),
+			newPath:       filepath.Join(tempDir, "folded.txt"),
+			createFile:    true,
+			linkOldPath:   true,
+			beingRenamed:  map[string]bool{},
+			caseSensitive: true,
+			expected:      false,
+		},
+		{
+			name:          "same_file_under_other_name_collides",
+			oldPath:       filepath.Join(tempDir, "alias.txt"),
+			newPath:       filepath.Join(tempDir, "target.txt"),
+			createFile:    true,
+			linkOldPath:   true,
+			beingRenamed:  map[string]bool{},
+			caseSensitive: true,
+			expected:      true,
+		},
 		{
 			name:          "file_does_not_exist",
 			newPath:       filepath.Join(tempDir, "nonexistent.txt"),
@@ -436,12 +641,20 @@ func TestHasDiskCollision(t *testing.T) {
 				}
 				defer os.Remove(tt.newPath)
 			}
+			// A hard link stands in for a case-folding filesystem, where
+			// both names resolve to the same file.
+			if tt.linkOldPath {
+				if err := os.Link(tt.newPath, tt.oldPath); err != nil {
+					t.Fatalf("failed to link test file: %v", err)
+				}
+				defer os.Remove(tt.oldPath)
+			}
 
 			adapter := &mockAdapter{caseSensitive: tt.caseSensitive}
 			engine := NewEngine(nil, adapter)
 
 			compareKey := compareKey(tt.newPath, tt.caseSensitive)
-			result := engine.hasDiskCollision(tt.newPath, compareKey, tt.beingRenamed)
+			result := engine.hasDiskCollision(tt.oldPath, tt.newPath, compareKey, tt.beingRenamed)
 			assert.Equal(t, result, tt.expected)
 		})
 	}
@@ -483,7 +696,7 @@ func TestPathDepth(t *testing.T) {
 	}
 }
 
-func TestSortPathsByDepth(t *testing.T) {
+func TestSortOpsByDepth(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    []string
@@ -548,11 +761,15 @@ func TestSortPathsByDepth(t *testing.T) {
 			mode := mockMode{transform: func(s string) string { return "upper" }}
 			adapter := &mockAdapter{caseSensitive: true}
 			engine := NewEngine(mode, adapter)
-			result := engine.SortPathsByDepth(tt.input)
+			ops := make([]RenameOp, len(tt.input))
+			for i, path := range tt.input {
+				ops[i] = RenameOp{OldPath: path, NewPath: path + "_new"}
+			}
+			result := engine.SortOpsByDepth(ops)
 			assert.Len(t, result, len(tt.expected))
 			for i, expected := range tt.expected {
 				if i < len(result) {
-					assert.Equal(t, result[i], expected)
+					assert.Equal(t, result[i].OldPath, expected)
 				}
 			}
 		})
@@ -1,8 +1,13 @@
 package engine
 
 import (
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"unicode"
+
+	"github.com/MSmaili/renym/internal/metadata"
 )
 
 type RenameMode interface {
@@ -21,6 +26,46 @@ var ModeRegistry = map[string]RenameMode{
 	"sentence":  SentenceCaseMode{},
 }
 
+// parameterizedModes are the modes NewMode builds from ModeOptions.
+var parameterizedModes = []string{"replace", "truncate", "template"}
+
+// ModeNames returns every mode name NewMode accepts.
+func ModeNames() []string {
+	return append(slices.Sorted(maps.Keys(ModeRegistry)), parameterizedModes...)
+}
+
+// ModeOptions carries the parameters of modes that are not plain case conversions.
+type ModeOptions struct {
+	Find         string
+	Replace      string
+	Template     string
+	NumberStart  int
+	NumberPerDir bool
+	MaxLength    int
+	Metadata     metadata.MetadataProvider
+}
+
+// NewMode resolves a mode by name, building parameterized modes from opts.
+func NewMode(name string, opts ModeOptions) (RenameMode, error) {
+	switch name {
+	case "replace":
+		return NewReplaceMode(opts.Find, opts.Replace)
+	case "truncate":
+		return NewTruncateMode(opts.MaxLength)
+	case "template":
+		mode, err := NewTemplateMode(opts.Template, opts.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		return mode.WithNumbering(opts.NumberStart, opts.NumberPerDir), nil
+	}
+
+	if mode, ok := ModeRegistry[name]; ok {
+		return mode, nil
+	}
+	return nil, fmt.Errorf("unknown mode '%s'", name)
+}
+
 type UpperCaseMode struct{}
 
 func (u UpperCaseMode) Transform(input string) string {
@@ -125,3 +125,61 @@ func TestBoundaries(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		find    string
+		replace string
+		in      string
+		want    string
+	}{
+		{"strip_prefix", "^IMG_", "", "IMG_1234", "1234"},
+		{"numbered_groups", `^(\d{4})-(\d{2})-(\d{2})`, "$3.$2.$1", "2024-05-17 report", "17.05.2024 report"},
+		{"named_groups", `(?P<word>\w+) copy`, "${word}", "draft copy", "draft"},
+		{"replace_all_matches", " ", "_", "a b c", "a_b_c"},
+		{"no_match_unchanged", "^zzz", "x", "photo", "photo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := NewReplaceMode(tt.find, tt.replace)
+			assert.Nil(t, err)
+			assert.Equal(t, mode.Transform(tt.in), tt.want)
+		})
+	}
+}
+
+func TestNewReplaceModeErrors(t *testing.T) {
+	_, err := NewReplaceMode("", "x")
+	assert.NotNil(t, err)
+
+	_, err = NewReplaceMode("([a-z", "x")
+	assert.NotNil(t, err)
+}
+
+func TestNewMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		opts      ModeOptions
+		expectErr bool
+	}{
+		{"registry_mode", "snake", ModeOptions{}, false},
+		{"replace_mode", "replace", ModeOptions{Find: "^IMG_"}, false},
+		{"replace_without_find", "replace", ModeOptions{}, true},
+		{"unknown_mode", "nope", ModeOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := NewMode(tt.mode, tt.opts)
+			if tt.expectErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.NotNil(t, mode)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ModeStep is one entry of a mode spec such as "truncate:40".
+type ModeStep struct {
+	Name string
+	Arg  string
+}
+
+// PipelineMode applies several modes to the same name, in order.
+type PipelineMode struct {
+	steps []RenameMode
+}
+
+func (p PipelineMode) Transform(input string) string {
+	for _, step := range p.steps {
+		input = step.Transform(input)
+	}
+	return input
+}
+
+// ParseModeSteps splits a mode spec on commas into its steps. A comma that is
+// part of an argument, e.g. in a regex like "\d{1,3}", can be escaped as "\,".
+func ParseModeSteps(spec string) []ModeStep {
+	var steps []ModeStep
+	for _, raw := range splitUnescaped(spec, ',') {
+		raw = strings.ReplaceAll(raw, `\,`, ",")
+		name, arg, _ := strings.Cut(raw, ":")
+		steps = append(steps, ModeStep{Name: strings.TrimSpace(name), Arg: arg})
+	}
+	return steps
+}
+
+// ValidateModeSpec checks that every step of spec names a known mode.
+func ValidateModeSpec(spec string) error {
+	names := ModeNames()
+	for _, step := range ParseModeSteps(spec) {
+		if !slices.Contains(names, step.Name) {
+			return fmt.Errorf("invalid mode '%s'. Valid modes are: %s", step.Name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// NewModeFromSpec builds the mode described by spec, either a single mode or a
+// pipeline such as "replace:^IMG_=,snake,truncate:40". Step arguments take
+// precedence over the matching fields of opts.
+func NewModeFromSpec(spec string, opts ModeOptions) (RenameMode, error) {
+	steps := ParseModeSteps(spec)
+
+	modes := make([]RenameMode, 0, len(steps))
+	for _, step := range steps {
+		if step.Name == "template" && len(steps) > 1 {
+			return nil, fmt.Errorf("template mode cannot be combined with other modes")
+		}
+
+		stepOpts, err := applyStepArg(step, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		mode, err := NewMode(step.Name, stepOpts)
+		if err != nil {
+			return nil, err
+		}
+		modes = append(modes, mode)
+	}
+
+	if len(modes) == 1 {
+		return modes[0], nil
+	}
+	return PipelineMode{steps: modes}, nil
+}
+
+func applyStepArg(step ModeStep, opts ModeOptions) (ModeOptions, error) {
+	if step.Arg == "" {
+		return opts, nil
+	}
+
+	switch step.Name {
+	case "replace":
+		parts := splitUnescaped(step.Arg, '=')
+		for i, part := range parts {
+			parts[i] = strings.ReplaceAll(part, `\=`, "=")
+		}
+		opts.Find = parts[0]
+		opts.Replace = strings.Join(parts[1:], "=")
+	case "truncate":
+		length, err := strconv.Atoi(step.Arg)
+		if err != nil {
+			return opts, fmt.Errorf("invalid truncate length '%s'", step.Arg)
+		}
+		opts.MaxLength = length
+	default:
+		return opts, fmt.Errorf("mode '%s' does not take an argument", step.Name)
+	}
+
+	return opts, nil
+}
+
+// splitUnescaped splits s on every sep that is not preceded by a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
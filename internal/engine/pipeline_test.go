@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestParseModeSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []ModeStep
+	}{
+		{"single_mode", "snake", []ModeStep{{Name: "snake"}}},
+		{"mode_with_arg", "truncate:40", []ModeStep{{Name: "truncate", Arg: "40"}}},
+		{
+			name: "pipeline",
+			spec: "replace:^IMG_=,snake,truncate:40",
+			expected: []ModeStep{
+				{Name: "replace", Arg: "^IMG_="},
+				{Name: "snake"},
+				{Name: "truncate", Arg: "40"},
+			},
+		},
+		{
+			name: "escaped_comma_in_arg",
+			spec: `replace:\d{1\,3}=N,kebab`,
+			expected: []ModeStep{
+				{Name: "replace", Arg: `\d{1,3}=N`},
+				{Name: "kebab"},
+			},
+		},
+		{"arg_keeps_colons", "replace:a:b=c", []ModeStep{{Name: "replace", Arg: "a:b=c"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := ParseModeSteps(tt.spec)
+			assert.SliceEqual(t, steps, tt.expected)
+		})
+	}
+}
+
+func TestNewModeFromSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		opts     ModeOptions
+		input    string
+		expected string
+	}{
+		{"single_mode", "snake", ModeOptions{}, "Hello World", "hello_world"},
+		{"replace_uses_flag_options", "replace", ModeOptions{Find: "^IMG_", Replace: "pic "}, "IMG_001", "pic 001"},
+		{"replace_arg_overrides_options", "replace:^IMG_=", ModeOptions{Find: "x"}, "IMG_001", "001"},
+		{"pipeline_in_order", "replace:^IMG_=,snake", ModeOptions{}, "IMG_Summer Trip", "summer_trip"},
+		{"pipeline_with_truncate", "kebab,truncate:10", ModeOptions{}, "My Summer Holiday", "my-summer"},
+		{"replacement_keeps_equals", "replace:-=a=b", ModeOptions{}, "x-y", "xa=by"},
+		{"escaped_comma_in_regex", `replace:\d{1\,3}=N,lower`, ModeOptions{}, "A123B", "anb"},
+		{"escaped_equals_in_find", `replace:a\=b=c`, ModeOptions{}, "xa=by", "xcy"},
+		{"escaped_equals_in_replacement", `replace:-=a\=b`, ModeOptions{}, "x-y", "xa=by"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := NewModeFromSpec(tt.spec, tt.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, mode.Transform(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestNewModeFromSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"unknown_step", "snake,shout"},
+		{"arg_on_plain_mode", "snake:1"},
+		{"invalid_truncate_length", "truncate:abc"},
+		{"truncate_without_length", "truncate"},
+		{"template_in_pipeline", "template,snake"},
+		{"invalid_regex", "replace:(=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewModeFromSpec(tt.spec, ModeOptions{Template: "{name}"})
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestValidateModeSpec(t *testing.T) {
+	for _, name := range ModeNames() {
+		assert.Nil(t, ValidateModeSpec(name))
+
+		_, err := NewMode(name, ModeOptions{Find: "a", MaxLength: 1, Template: "{name}"})
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, ValidateModeSpec("replace:^IMG_=,snake,truncate:40"))
+	assert.NotNil(t, ValidateModeSpec("snake,shout"))
+	assert.NotNil(t, ValidateModeSpec(""))
+}
+
+func TestTruncateMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		input    string
+		expected string
+	}{
+		{"shorter_unchanged", 10, "short", "short"},
+		{"exact_length_unchanged", 5, "exact", "exact"},
+		{"cut", 4, "truncated", "trun"},
+		{"trailing_separator_trimmed", 4, "abc_def", "abc"},
+		{"counts_runes", 3, "äöüß", "äöü"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := NewTruncateMode(tt.max)
+			assert.Nil(t, err)
+			assert.Equal(t, mode.Transform(tt.input), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ReplaceMode applies a regular expression find-and-replace to the name.
+// The replacement may reference capture groups using $1 or ${name}.
+type ReplaceMode struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func NewReplaceMode(find, replacement string) (ReplaceMode, error) {
+	if find == "" {
+		return ReplaceMode{}, fmt.Errorf("replace mode requires a find pattern")
+	}
+
+	pattern, err := regexp.Compile(find)
+	if err != nil {
+		return ReplaceMode{}, fmt.Errorf("invalid find pattern '%s': %w", find, err)
+	}
+
+	return ReplaceMode{
+		pattern:     pattern,
+		replacement: replacement,
+	}, nil
+}
+
+func (r ReplaceMode) Transform(input string) string {
+	return r.pattern.ReplaceAllString(input, r.replacement)
+}
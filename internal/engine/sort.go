@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/MSmaili/renym/internal/metadata"
+)
+
+// SortPaths returns paths ordered by name, natural, mtime or size so that
+// sequence numbers are assigned predictably. Ties on mtime and size fall
+// back to natural order.
+func SortPaths(paths []string, order string, provider metadata.MetadataProvider) ([]string, error) {
+	sorted := make([]string, len(paths))
+	copy(sorted, paths)
+
+	switch order {
+	case "name":
+		sort.Strings(sorted)
+	case "natural":
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return naturalLess(sorted[i], sorted[j])
+		})
+	case "mtime", "size":
+		metas := make(map[string]*metadata.FileMetadata, len(sorted))
+		for _, path := range sorted {
+			meta, err := provider.GetMetadata(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read metadata for %s: %w", path, err)
+			}
+			metas[path] = meta
+		}
+
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := metas[sorted[i]], metas[sorted[j]]
+			if order == "mtime" && !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.Before(b.ModTime)
+			}
+			if order == "size" && a.Size != b.Size {
+				return a.Size < b.Size
+			}
+			return naturalLess(sorted[i], sorted[j])
+		})
+	default:
+		return nil, fmt.Errorf("invalid sort order '%s'", order)
+	}
+
+	return sorted, nil
+}
+
+// naturalLess compares strings treating runs of digits as numbers,
+// so "img2" sorts before "img10".
+func naturalLess(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			startA, startB := i, j
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+
+			numA := strings.TrimLeft(string(ra[startA:i]), "0")
+			numB := strings.TrimLeft(string(rb[startB:j]), "0")
+			if len(numA) != len(numB) {
+				return len(numA) < len(numB)
+			}
+			if numA != numB {
+				return numA < numB
+			}
+			continue
+		}
+
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
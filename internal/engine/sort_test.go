@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+	"github.com/MSmaili/renym/internal/metadata"
+)
+
+type mapMetadataProvider map[string]*metadata.FileMetadata
+
+func (m mapMetadataProvider) GetMetadata(path string) (*metadata.FileMetadata, error) {
+	return m[path], nil
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"numbers_compared_numerically", "img2", "img10", true},
+		{"numbers_compared_numerically_reverse", "img10", "img2", false},
+		{"plain_text", "alpha", "beta", true},
+		{"prefix_is_less", "img", "img1", true},
+		{"leading_zeros_equal_value", "img01", "img1", true},
+		{"equal", "same", "same", false},
+		{"chapters", "chapter 9.md", "chapter 11.md", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, naturalLess(tt.a, tt.b), tt.want)
+		})
+	}
+}
+
+func TestSortPaths(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	provider := mapMetadataProvider{
+		"a10.jpg": {ModTime: base.Add(2 * time.Hour), Size: 30},
+		"a2.jpg":  {ModTime: base, Size: 30},
+		"a1.jpg":  {ModTime: base.Add(time.Hour), Size: 10},
+	}
+	paths := []string{"a10.jpg", "a2.jpg", "a1.jpg"}
+
+	tests := []struct {
+		name     string
+		order    string
+		expected []string
+	}{
+		{"name", "name", []string{"a1.jpg", "a10.jpg", "a2.jpg"}},
+		{"natural", "natural", []string{"a1.jpg", "a2.jpg", "a10.jpg"}},
+		{"mtime", "mtime", []string{"a2.jpg", "a1.jpg", "a10.jpg"}},
+		{"size_ties_fall_back_to_natural", "size", []string{"a1.jpg", "a2.jpg", "a10.jpg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SortPaths(paths, tt.order, provider)
+			assert.Nil(t, err)
+			assert.SliceEqual(t, result, tt.expected)
+		})
+	}
+
+	t.Run("invalid_order", func(t *testing.T) {
+		_, err := SortPaths(paths, "random", provider)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,227 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/MSmaili/renym/internal/metadata"
+)
+
+// FileRenameMode is implemented by modes that build the whole new name,
+// extension included, from the file itself rather than from its base name alone.
+type FileRenameMode interface {
+	RenameMode
+	TransformFile(path, name string) (string, error)
+}
+
+const defaultTimeLayout = "2006-01-02"
+
+// maxNumberWidth caps the zero padding of {n:<width>}.
+const maxNumberWidth = 20
+
+type templateSegment struct {
+	literal string
+	token   string
+	arg     string
+}
+
+// templateContext holds the values a single file contributes to the template.
+type templateContext struct {
+	path   string
+	name   string
+	meta   *metadata.FileMetadata
+	number int
+}
+
+// TemplateMode renders names from a template such as "{mtime:2006-01-02}_{name:snake}{ext}".
+//
+// Supported tokens:
+//   - {name} or {name:<mode>}: original name without extension, optionally transformed
+//   - {ext}: original extension including the leading dot
+//   - {parent}: name of the parent directory
+//   - {size}: size in bytes
+//   - {mtime} or {mtime:<layout>}: modification time, formatted with a Go time layout
+//   - {ctime} or {ctime:<layout>}: creation time, formatted with a Go time layout
+//   - {n} or {n:<width>}: sequence number, zero-padded to width, e.g. {n:03}
+type TemplateMode struct {
+	segments []templateSegment
+	provider metadata.MetadataProvider
+	counter  *Counter
+	perDir   bool
+}
+
+func NewTemplateMode(template string, provider metadata.MetadataProvider) (*TemplateMode, error) {
+	if template == "" {
+		return nil, fmt.Errorf("template mode requires a template")
+	}
+
+	segments, err := parseTemplate(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template '%s': %w", template, err)
+	}
+
+	return &TemplateMode{
+		segments: segments,
+		provider: provider,
+		counter:  NewCounter(1),
+	}, nil
+}
+
+// WithNumbering sets where {n} starts and whether each directory gets its own sequence.
+func (t *TemplateMode) WithNumbering(start int, perDir bool) *TemplateMode {
+	if perDir {
+		t.counter = NewDirCounter(start)
+	} else {
+		t.counter = NewCounter(start)
+	}
+	t.perDir = perDir
+	return t
+}
+
+// Transform returns the input unchanged, templates need the file itself
+// and are rendered through TransformFile.
+func (t *TemplateMode) Transform(input string) string {
+	return input
+}
+
+func (t *TemplateMode) TransformFile(path, name string) (string, error) {
+	ctx := templateContext{path: path, name: name}
+
+	if t.uses("size", "mtime", "ctime") {
+		meta, err := t.provider.GetMetadata(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read metadata: %w", err)
+		}
+		ctx.meta = meta
+	}
+
+	if t.uses("n") {
+		dir := ""
+		if t.perDir {
+			dir = filepath.Dir(path)
+		}
+		ctx.number = t.counter.Next(dir)
+	}
+
+	var sb strings.Builder
+	for _, seg := range t.segments {
+		if seg.token == "" {
+			sb.WriteString(seg.literal)
+			continue
+		}
+		sb.WriteString(renderToken(seg, ctx))
+	}
+
+	return sb.String(), nil
+}
+
+func renderToken(seg templateSegment, ctx templateContext) string {
+	switch seg.token {
+	case "name":
+		if seg.arg == "" {
+			return ctx.name
+		}
+		return ModeRegistry[seg.arg].Transform(ctx.name)
+	case "ext":
+		return filepath.Ext(filepath.Base(ctx.path))
+	case "parent":
+		return parentName(ctx.path)
+	case "size":
+		return strconv.FormatInt(ctx.meta.Size, 10)
+	case "mtime":
+		return ctx.meta.ModTime.Format(timeLayout(seg.arg))
+	case "ctime":
+		return ctx.meta.CreatedTime.Format(timeLayout(seg.arg))
+	case "n":
+		if seg.arg == "" {
+			return strconv.Itoa(ctx.number)
+		}
+		width, _ := strconv.Atoi(seg.arg)
+		return fmt.Sprintf("%0*d", width, ctx.number)
+	}
+	return ""
+}
+
+// uses reports whether the template contains any of the given tokens.
+func (t *TemplateMode) uses(tokens ...string) bool {
+	for _, seg := range t.segments {
+		if slices.Contains(tokens, seg.token) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTemplate(template string) ([]templateSegment, error) {
+	var segments []templateSegment
+
+	rest := template
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open == -1 {
+			segments = append(segments, templateSegment{literal: rest})
+			break
+		}
+
+		if open > 0 {
+			segments = append(segments, templateSegment{literal: rest[:open]})
+		}
+
+		end := strings.IndexByte(rest[open:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("unclosed '{' at position %d", len(template)-len(rest)+open)
+		}
+
+		seg, err := parseToken(rest[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+
+		rest = rest[open+end+1:]
+	}
+
+	return segments, nil
+}
+
+func parseToken(body string) (templateSegment, error) {
+	token, arg, _ := strings.Cut(body, ":")
+
+	switch token {
+	case "name":
+		if _, ok := ModeRegistry[arg]; arg != "" && !ok {
+			return templateSegment{}, fmt.Errorf("unknown mode '%s' in {%s}", arg, body)
+		}
+	case "ext", "parent", "size":
+		if arg != "" {
+			return templateSegment{}, fmt.Errorf("token {%s} does not take an argument", token)
+		}
+	case "n":
+		if width, err := strconv.Atoi(arg); arg != "" && (err != nil || width < 0 || width > maxNumberWidth) {
+			return templateSegment{}, fmt.Errorf("invalid width '%s' in {%s}, must be 0 to %d", arg, body, maxNumberWidth)
+		}
+	case "mtime", "ctime":
+	default:
+		return templateSegment{}, fmt.Errorf("unknown token {%s}", body)
+	}
+
+	return templateSegment{token: token, arg: arg}, nil
+}
+
+func timeLayout(layout string) string {
+	if layout == "" {
+		return defaultTimeLayout
+	}
+	return layout
+}
+
+func parentName(path string) string {
+	dir := filepath.Dir(path)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return filepath.Base(dir)
+}
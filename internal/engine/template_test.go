@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+	"github.com/MSmaili/renym/internal/metadata"
+)
+
+type mockMetadataProvider struct {
+	meta *metadata.FileMetadata
+	err  error
+}
+
+func (m *mockMetadataProvider) GetMetadata(path string) (*metadata.FileMetadata, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.meta, nil
+}
+
+func TestTemplateModeTransformFile(t *testing.T) {
+	provider := &mockMetadataProvider{meta: &metadata.FileMetadata{
+		ModTime:     time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC),
+		CreatedTime: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		Size:        2048,
+		Extension:   ".pdf",
+	}}
+
+	tests := []struct {
+		name     string
+		template string
+		path     string
+		input    string
+		expected string
+	}{
+		{"name_and_ext", "{name}{ext}", "/reports/Q1 Report.pdf", "Q1 Report", "Q1 Report.pdf"},
+		{"name_with_mode", "{name:snake}{ext}", "/reports/Q1 Report.pdf", "Q1 Report", "q_1_report.pdf"},
+		{"default_mtime_layout", "{mtime}_{name}{ext}", "/reports/a.pdf", "a", "2024-05-17_a.pdf"},
+		{"custom_mtime_layout", "{mtime:20060102-1504}{ext}", "/reports/a.pdf", "a", "20240517-0930.pdf"},
+		{"ctime", "{ctime:2006}_{name}{ext}", "/reports/a.pdf", "a", "2023_a.pdf"},
+		{"size", "{name}-{size}{ext}", "/reports/a.pdf", "a", "a-2048.pdf"},
+		{"parent", "{parent}_{name}{ext}", "/reports/2024/a.pdf", "a", "2024_a.pdf"},
+		{"literal_only", "fixed.txt", "/reports/a.pdf", "a", "fixed.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := NewTemplateMode(tt.template, provider)
+			assert.Nil(t, err)
+
+			result, err := mode.TransformFile(tt.path, tt.input)
+			assert.Nil(t, err)
+			assert.Equal(t, result, tt.expected)
+		})
+	}
+}
+
+func TestTemplateModeMetadataError(t *testing.T) {
+	provider := &mockMetadataProvider{err: errors.New("stat failed")}
+
+	mode, err := NewTemplateMode("{mtime}{ext}", provider)
+	assert.Nil(t, err)
+
+	_, err = mode.TransformFile("/a.txt", "a")
+	assert.NotNil(t, err)
+
+	// Templates without metadata tokens never hit the provider
+	mode, err = NewTemplateMode("{name:upper}{ext}", provider)
+	assert.Nil(t, err)
+
+	result, err := mode.TransformFile("/a.txt", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, result, "A.txt")
+}
+
+func TestNewTemplateModeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"empty", ""},
+		{"unclosed_brace", "{name"},
+		{"unknown_token", "{owner}"},
+		{"unknown_name_mode", "{name:shouting}"},
+		{"argument_not_allowed", "{ext:upper}"},
+		{"invalid_number_width", "{n:abc}"},
+		{"negative_number_width", "{n:-3}"},
+		{"number_width_too_large", "{n:1000000}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTemplateMode(tt.template, &mockMetadataProvider{})
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestPlanWithTemplateMode(t *testing.T) {
+	tempDir := t.TempDir()
+	provider := &mockMetadataProvider{meta: &metadata.FileMetadata{
+		ModTime: time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC),
+	}}
+
+	mode, err := NewTemplateMode("{mtime}_{name:kebab}{ext}", provider)
+	assert.Nil(t, err)
+
+	engine := NewEngine(mode, &mockAdapter{caseSensitive: true})
+	result := engine.Plan([]string{filepath.Join(tempDir, "Monthly Export.csv")})
+
+	assert.Len(t, result.Operations, 1)
+	assert.Equal(t, result.Operations[0].NewPath, filepath.Join(tempDir, "2024-05-17_monthly-export.csv"))
+}
+
+func TestTemplateModeNumbering(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		start    int
+		perDir   bool
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "global_sequence",
+			template: "photo_{n:03}{ext}",
+			start:    1,
+			paths:    []string{"/a/x.jpg", "/b/y.jpg", "/a/z.jpg"},
+			expected: []string{"photo_001.jpg", "photo_002.jpg", "photo_003.jpg"},
+		},
+		{
+			name:     "custom_start_without_padding",
+			template: "{n}-{name}{ext}",
+			start:    10,
+			paths:    []string{"/a/x.jpg", "/a/y.jpg"},
+			expected: []string{"10-x.jpg", "11-y.jpg"},
+		},
+		{
+			name:     "per_directory_sequence",
+			template: "{parent}_{n:2}{ext}",
+			start:    1,
+			perDir:   true,
+			paths:    []string{"/a/x.jpg", "/b/y.jpg", "/a/z.jpg"},
+			expected: []string{"a_01.jpg", "b_01.jpg", "a_02.jpg"},
+		},
+		{
+			name:     "number_used_twice_is_the_same",
+			template: "{n}_{n}{ext}",
+			start:    1,
+			paths:    []string{"/a/x.jpg", "/a/y.jpg"},
+			expected: []string{"1_1.jpg", "2_2.jpg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := NewTemplateMode(tt.template, &mockMetadataProvider{})
+			assert.Nil(t, err)
+			mode.WithNumbering(tt.start, tt.perDir)
+
+			for i, path := range tt.paths {
+				name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+				result, err := mode.TransformFile(path, name)
+				assert.Nil(t, err)
+				assert.Equal(t, result, tt.expected[i])
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TruncateMode shortens names to at most maxLength characters, dropping any
+// separators left dangling at the cut.
+type TruncateMode struct {
+	maxLength int
+}
+
+func NewTruncateMode(maxLength int) (TruncateMode, error) {
+	if maxLength <= 0 {
+		return TruncateMode{}, fmt.Errorf("truncate mode requires a positive length, e.g. truncate:40")
+	}
+	return TruncateMode{maxLength: maxLength}, nil
+}
+
+func (t TruncateMode) Transform(input string) string {
+	r := []rune(input)
+	if len(r) <= t.maxLength {
+		return input
+	}
+	return strings.TrimRightFunc(string(r[:t.maxLength]), isDelimiter)
+}
@@ -0,0 +1,153 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CheckDrift reports files that changed since history was recorded: every
+// source must still exist and match its recorded identity, and no target may
+// be taken by a file outside the batch. Ops run in order, so paths inside a
+// directory renamed by an earlier op are resolved to where that directory is
+// now.
+func CheckDrift(adapter FileSystemAdapter, ops []RenameOp) error {
+	index := NewRenameIndex(ops)
+	caseSensitive := adapter.IsCaseSensitive()
+
+	sources := make(map[string]bool, len(ops))
+	onDisk := make([]string, len(ops))
+	for i, op := range ops {
+		onDisk[i] = index.onDisk(i, op.OldPath)
+		sources[onDisk[i]] = true
+	}
+
+	var problems []string
+	for i, op := range ops {
+		source := onDisk[i]
+		if _, err := os.Lstat(source); err != nil {
+			problems = append(problems, fmt.Sprintf("%s no longer exists", op.OldPath))
+		} else if problem := identityDrift(adapter, source, op.Identity); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s %s", op.OldPath, problem))
+		}
+
+		// On a case-insensitive filesystem the target of a case-only rename
+		// is the source itself
+		target := index.onDisk(i, op.NewPath)
+		if _, err := os.Lstat(target); err == nil && !sources[target] && !isCaseOnlyRename(source, target, caseSensitive) {
+			problems = append(problems, fmt.Sprintf("%s already exists", op.NewPath))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("files changed since the rename was recorded, nothing was renamed:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// identityDrift describes how the file at path differs from want, or returns
+// an empty string if it matches or no identity was recorded.
+func identityDrift(adapter FileSystemAdapter, path string, want *FileIdentity) string {
+	if want == nil {
+		return ""
+	}
+
+	got, err := Identify(adapter, path)
+	if err != nil {
+		return fmt.Sprintf("could not be checked: %v", err)
+	}
+	if got.ID != want.ID {
+		return "was replaced by another file"
+	}
+	if got.Modified(*want) {
+		return "was modified"
+	}
+	return ""
+}
+
+// RenameIndex relocates paths through renames of their parent directories.
+// Ops are looked up by the directory they renamed, so relocating every path
+// of a large batch does not scan the whole batch for each path.
+type RenameIndex struct {
+	ops []RenameOp
+	// byOld and byNew hold the indexes of the ops with a path, in order
+	byOld map[string][]int
+	byNew map[string][]int
+}
+
+// NewRenameIndex indexes ops, which must be in the order the renames run.
+func NewRenameIndex(ops []RenameOp) *RenameIndex {
+	index := &RenameIndex{
+		ops:   ops,
+		byOld: make(map[string][]int, len(ops)),
+		byNew: make(map[string][]int, len(ops)),
+	}
+	for i, op := range ops {
+		oldPath, newPath := filepath.Clean(op.OldPath), filepath.Clean(op.NewPath)
+		index.byOld[oldPath] = append(index.byOld[oldPath], i)
+		index.byNew[newPath] = append(index.byNew[newPath], i)
+	}
+	return index
+}
+
+// FinalPath returns where path is once every op after ops[i] ran, applying
+// later ops that renamed one of its parent directories.
+func (x *RenameIndex) FinalPath(i int, path string) string {
+	for from := i + 1; ; {
+		// The first op from on that renamed a parent moves path
+		next, dir := -1, ""
+		for _, parent := range parents(path) {
+			indexes := x.byOld[parent]
+			if j := sort.SearchInts(indexes, from); j < len(indexes) && (next < 0 || indexes[j] < next) {
+				next, dir = indexes[j], parent
+			}
+		}
+		if next < 0 {
+			return path
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		path = filepath.Join(x.ops[next].NewPath, rel)
+		from = next + 1
+	}
+}
+
+// Relocate returns where path is once every op ran, for a path recorded
+// before.
+func (x *RenameIndex) Relocate(path string) string {
+	return x.FinalPath(-1, path)
+}
+
+// onDisk returns where path is before ops[i] runs, undoing the effect of
+// earlier ops that renamed one of its parent directories.
+func (x *RenameIndex) onDisk(i int, path string) string {
+	for until := i; ; {
+		// The last op before until that renamed a parent moved path
+		prev, dir := -1, ""
+		for _, parent := range parents(path) {
+			indexes := x.byNew[parent]
+			if j := sort.SearchInts(indexes, until) - 1; j >= 0 && indexes[j] > prev {
+				prev, dir = indexes[j], parent
+			}
+		}
+		if prev < 0 {
+			return path
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		path = filepath.Join(x.ops[prev].OldPath, rel)
+		until = prev
+	}
+}
+
+// parents returns every parent directory of path, nearest first.
+func parents(path string) []string {
+	var dirs []string
+	for dir := filepath.Clean(path); filepath.Dir(dir) != dir; {
+		dir = filepath.Dir(dir)
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
@@ -0,0 +1,288 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils"
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestCheckDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		ops      []RenameOp
+		wantErr  bool
+	}{
+		{
+			name:     "unchanged",
+			existing: []string{"a.txt"},
+			ops:      []RenameOp{{OldPath: "a.txt", NewPath: "b.txt"}},
+		},
+		{
+			name:    "source_gone",
+			ops:     []RenameOp{{OldPath: "a.txt", NewPath: "b.txt"}},
+			wantErr: true,
+		},
+		{
+			name:     "target_taken",
+			existing: []string{"a.txt", "b.txt"},
+			ops:      []RenameOp{{OldPath: "a.txt", NewPath: "b.txt"}},
+			wantErr:  true,
+		},
+		{
+			name:     "target_freed_by_batch",
+			existing: []string{"a.txt", "b.txt"},
+			ops: []RenameOp{
+				{OldPath: "a.txt", NewPath: "b.txt"},
+				{OldPath: "b.txt", NewPath: "a.txt"},
+			},
+		},
+		{
+			// Undo renames the parent back first, the child is then found
+			// under the parent's restored name
+			name:     "inside_directory_renamed_earlier",
+			existing: []string{"folder/renamed.txt"},
+			ops: []RenameOp{
+				{OldPath: "folder", NewPath: "dir"},
+				{OldPath: "dir/renamed.txt", NewPath: "dir/file.txt"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			testutils.CreateFiles(t, root, tt.existing)
+
+			ops := make([]RenameOp, len(tt.ops))
+			for i, op := range tt.ops {
+				ops[i] = RenameOp{
+					OldPath: filepath.Join(root, filepath.FromSlash(op.OldPath)),
+					NewPath: filepath.Join(root, filepath.FromSlash(op.NewPath)),
+				}
+			}
+
+			err := CheckDrift(NewAdapter(), ops)
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected drift error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// caseInsensitiveAdapter reports a case-insensitive filesystem, where Foo.txt
+// and foo.txt are the same file.
+type caseInsensitiveAdapter struct {
+	FileSystemAdapter
+}
+
+func (caseInsensitiveAdapter) IsCaseSensitive() bool { return false }
+
+func TestCheckDriftCaseOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		adapter FileSystemAdapter
+		wantErr bool
+	}{
+		// Foo.txt and foo.txt stand in for the one file a case-insensitive
+		// filesystem finds under both names
+		{name: "case_insensitive", adapter: caseInsensitiveAdapter{NewAdapter()}},
+		{name: "case_sensitive", adapter: NewAdapter(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.adapter.IsCaseSensitive() && !NewAdapter().IsCaseSensitive() {
+				t.Skip("needs a case-sensitive filesystem")
+			}
+			root := t.TempDir()
+			testutils.CreateFiles(t, root, []string{"Foo.txt", "foo.txt"})
+
+			err := CheckDrift(tt.adapter, []RenameOp{{
+				OldPath: filepath.Join(root, "Foo.txt"),
+				NewPath: filepath.Join(root, "foo.txt"),
+			}})
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected drift error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckDriftIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, path string)
+		wantErr bool
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, path string) {},
+		},
+		{
+			name: "modified",
+			change: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte("edited"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			// The new file is created before the old one is removed, so it
+			// cannot reuse the old inode
+			name: "replaced",
+			change: func(t *testing.T, path string) {
+				other := path + ".new"
+				if err := os.WriteFile(other, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(other, path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			testutils.CreateFiles(t, root, []string{"a.txt"})
+			path := filepath.Join(root, "a.txt")
+
+			adapter := NewAdapter()
+			identity, err := Identify(adapter, path)
+			assert.Nil(t, err)
+
+			tt.change(t, path)
+
+			err = CheckDrift(adapter, []RenameOp{{
+				OldPath:  path,
+				NewPath:  filepath.Join(root, "b.txt"),
+				Identity: &identity,
+			}})
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected drift error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFinalPath(t *testing.T) {
+	ops := []RenameOp{
+		{OldPath: filepath.Join("dir", "sub", "file.txt"), NewPath: filepath.Join("dir", "sub", "renamed.txt")},
+		{OldPath: filepath.Join("dir", "sub"), NewPath: filepath.Join("dir", "folder")},
+		{OldPath: "dir", NewPath: "top"},
+	}
+	index := NewRenameIndex(ops)
+
+	assert.Equal(t, index.FinalPath(0, ops[0].NewPath), filepath.Join("top", "folder", "renamed.txt"))
+	assert.Equal(t, index.FinalPath(1, ops[1].NewPath), filepath.Join("top", "folder"))
+	assert.Equal(t, index.FinalPath(2, ops[2].NewPath), "top")
+	assert.Equal(t, index.FinalPath(0, "other.txt"), "other.txt")
+}
+
+func TestRelocate(t *testing.T) {
+	index := NewRenameIndex([]RenameOp{
+		{OldPath: filepath.Join("dir", "sub"), NewPath: filepath.Join("dir", "folder")},
+		{OldPath: "dir", NewPath: "top"},
+	})
+
+	assert.Equal(t, index.Relocate(filepath.Join("dir", "sub", "a.txt")), filepath.Join("top", "folder", "a.txt"))
+	assert.Equal(t, index.Relocate(filepath.Join("dir", "b.txt")), filepath.Join("top", "b.txt"))
+	assert.Equal(t, index.Relocate("dir"), "dir")
+	assert.Equal(t, NewRenameIndex(nil).Relocate("a.txt"), "a.txt")
+}
+
+// TestRenameIndexOrder compares the index with applying every op in turn,
+// for ops renaming parents before and after their children.
+func TestRenameIndexOrder(t *testing.T) {
+	ops := []RenameOp{
+		{OldPath: filepath.Join("a", "b", "f1"), NewPath: filepath.Join("a", "b", "g1")},
+		{OldPath: "a", NewPath: "x"},
+		{OldPath: filepath.Join("x", "b"), NewPath: filepath.Join("x", "c")},
+		{OldPath: filepath.Join("x", "c", "f2"), NewPath: filepath.Join("x", "c", "g2")},
+		{OldPath: "x", NewPath: "a"},
+		{OldPath: filepath.Join("a", "c"), NewPath: filepath.Join("a", "b")},
+	}
+	index := NewRenameIndex(ops)
+
+	for i, op := range ops {
+		for _, path := range []string{op.OldPath, op.NewPath, filepath.Join(op.NewPath, "child")} {
+			assert.Equal(t, index.FinalPath(i, path), relocateEach(ops[i+1:], path))
+			assert.Equal(t, index.onDisk(i, path), onDiskEach(ops[:i], path))
+		}
+	}
+}
+
+// relocateEach and onDiskEach scan every op, they define what the index must
+// return.
+func relocateEach(ops []RenameOp, path string) string {
+	for _, op := range ops {
+		if rel, ok := within(op.OldPath, path); ok {
+			path = filepath.Join(op.NewPath, rel)
+		}
+	}
+	return path
+}
+
+func onDiskEach(ops []RenameOp, path string) string {
+	for k := len(ops) - 1; k >= 0; k-- {
+		if rel, ok := within(ops[k].NewPath, path); ok {
+			path = filepath.Join(ops[k].OldPath, rel)
+		}
+	}
+	return path
+}
+
+func within(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// BenchmarkRenameIndex relocates every path of a recursive rename of 50,000
+// files in 500 directories, which must stay linear in the number of ops.
+func BenchmarkRenameIndex(b *testing.B) {
+	var ops []RenameOp
+	for d := range 500 {
+		dir := filepath.Join("root", fmt.Sprintf("Dir%d", d))
+		for f := range 100 {
+			ops = append(ops, RenameOp{
+				OldPath: filepath.Join(dir, fmt.Sprintf("File%d.txt", f)),
+				NewPath: filepath.Join(dir, fmt.Sprintf("file%d.txt", f)),
+			})
+		}
+	}
+	for d := range 500 {
+		ops = append(ops, RenameOp{
+			OldPath: filepath.Join("root", fmt.Sprintf("Dir%d", d)),
+			NewPath: filepath.Join("root", fmt.Sprintf("dir%d", d)),
+		})
+	}
+
+	for b.Loop() {
+		index := NewRenameIndex(ops)
+		for i, op := range ops {
+			index.FinalPath(i, op.NewPath)
+			index.onDisk(i, op.OldPath)
+		}
+	}
+}
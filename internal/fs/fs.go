@@ -1,8 +1,13 @@
 package fs
 
 import (
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type FileSystemAdapter interface {
@@ -15,17 +20,222 @@ type FileSystemAdapter interface {
 type RenameOp struct {
 	OldPath string
 	NewPath string
+	// Identity, if set, is the expected identity of OldPath. It is only
+	// checked by CheckDrift, Apply ignores it.
+	Identity *FileIdentity
 }
 
-func Apply(ops []RenameOp, dryRun bool) error {
-	for _, op := range ops {
-		if dryRun {
+// ErrRollbackIncomplete is reported by Apply when a failed batch could not be
+// fully rolled back and some renames are still in place.
+var ErrRollbackIncomplete = errors.New("rollback incomplete")
+
+// RollbackError is returned by Apply, joined with the error that made the batch
+// fail, when the rollback left some renames in place. It matches
+// ErrRollbackIncomplete.
+type RollbackError struct {
+	// Remaining lists the renames still in place, in the order they were
+	// performed. They may include renames to temporary names.
+	Remaining []RenameOp
+	Err       error
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrRollbackIncomplete, e.Err)
+}
+
+func (e *RollbackError) Unwrap() []error {
+	return []error{ErrRollbackIncomplete, e.Err}
+}
+
+// Journal records every rename Apply performs, including renames to temporary
+// names and rollbacks, so an interrupted batch can be recovered.
+type Journal interface {
+	// Pending is called right before oldPath is renamed to newPath.
+	Pending(oldPath, newPath string) error
+	// Done is called once the last pending rename succeeded.
+	Done() error
+	// Sync flushes the records written so far to stable storage.
+	Sync() error
+}
+
+// ApplyOptions controls how Apply performs a batch of renames.
+type ApplyOptions struct {
+	DryRun bool
+	// CaseSensitive reports whether the filesystem distinguishes names that
+	// differ only in case, see FileSystemAdapter.IsCaseSensitive.
+	CaseSensitive bool
+	// Journal, if set, is written ahead of every rename.
+	Journal Journal
+}
+
+// Apply renames every op in order. If a rename fails, the renames already
+// performed are rolled back in reverse order, so the batch either fully
+// applies or leaves the filesystem as it was.
+//
+// Permutations such as swaps (a→b, b→a) and cycles (a→b→c→a) are supported:
+// when an op's target is still the source of a later op, that source is first
+// moved out of the way to a unique temporary name in the same directory.
+//
+// Case-only renames (Foo.txt → foo.txt) on case-insensitive filesystems also
+// go through a temporary name, since renaming a file onto itself is a no-op
+// or an error on some case-folding filesystems.
+func Apply(ops []RenameOp, opts ApplyOptions) error {
+	if opts.DryRun {
+		for _, op := range ops {
 			fmt.Printf("Would rename: %s -> %s\n", op.OldPath, op.NewPath)
-		} else {
-			if err := os.Rename(op.OldPath, op.NewPath); err != nil {
-				return fmt.Errorf("failed to rename %s to %s: %w", op.OldPath, op.NewPath, err)
+		}
+		return nil
+	}
+
+	// current tracks where each op's source lives now, pending maps the
+	// sources that still have to be renamed to their op index.
+	key := func(path string) string {
+		if !opts.CaseSensitive {
+			return strings.ToLower(path)
+		}
+		return path
+	}
+
+	current := make([]string, len(ops))
+	pending := make(map[string]int, len(ops))
+	for i, op := range ops {
+		current[i] = op.OldPath
+		pending[key(op.OldPath)] = i
+	}
+
+	// Recovery finds a rename that is missing from the journal by which of
+	// its names exists. That fails for temporary names and for names freed
+	// by an earlier rename and taken again, so those renames are synced.
+	var applied []RenameOp
+	vacated := make(map[string]bool, len(ops))
+	rename := func(oldPath, newPath string, staging bool) error {
+		durable := staging || vacated[key(newPath)]
+		if err := journalRename(opts.Journal, oldPath, newPath, durable); err != nil {
+			return err
+		}
+		applied = append(applied, RenameOp{OldPath: oldPath, NewPath: newPath})
+		vacated[key(oldPath)] = true
+		return nil
+	}
+	fail := func(err error) error {
+		if rollbackErr := rollback(applied, opts.Journal); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return fmt.Errorf("%w (rolled back %d completed rename(s))", err, len(applied))
+	}
+
+	for i, op := range ops {
+		delete(pending, key(current[i]))
+
+		if isCaseOnlyRename(current[i], op.NewPath, opts.CaseSensitive) {
+			staged, err := stagingPath(op.NewPath)
+			if err != nil {
+				return fail(err)
 			}
+			if err := rename(current[i], staged, true); err != nil {
+				return fail(fmt.Errorf("failed to stage %s as %s: %w", current[i], staged, err))
+			}
+			current[i] = staged
+		}
+
+		if j, blocked := pending[key(op.NewPath)]; blocked {
+			staged, err := stagingPath(op.NewPath)
+			if err != nil {
+				return fail(err)
+			}
+			if err := rename(current[j], staged, true); err != nil {
+				return fail(fmt.Errorf("failed to stage %s as %s: %w", current[j], staged, err))
+			}
+
+			delete(pending, key(current[j]))
+			current[j] = staged
+			pending[key(staged)] = j
+		}
+
+		if err := rename(current[i], op.NewPath, false); err != nil {
+			return fail(fmt.Errorf("failed to rename %s to %s: %w", op.OldPath, op.NewPath, err))
 		}
 	}
 	return nil
 }
+
+// journalRename performs a single no-replace rename, recording it in journal
+// before and after when one is given. With durable, the journal is synced
+// before the rename, so the rename is known after a power loss.
+func journalRename(journal Journal, oldPath, newPath string, durable bool) error {
+	if journal == nil {
+		return renameNoReplace(oldPath, newPath)
+	}
+
+	if err := journal.Pending(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if durable {
+		if err := journal.Sync(); err != nil {
+			return fmt.Errorf("failed to sync journal: %w", err)
+		}
+	}
+	if err := renameNoReplace(oldPath, newPath); err != nil {
+		return err
+	}
+	// The rename happened, so it must not be reported as failed. A missing
+	// done record is resolved on recovery by checking which name exists.
+	_ = journal.Done()
+	return nil
+}
+
+// isCaseOnlyRename reports whether oldPath and newPath name the same file
+// and differ only in case. Besides trusting caseSensitive, it checks whether
+// both names resolve to the same file, which catches case-folding mounts
+// (vfat, exfat, SMB) on otherwise case-sensitive systems.
+func isCaseOnlyRename(oldPath, newPath string, caseSensitive bool) bool {
+	if oldPath == newPath || !strings.EqualFold(oldPath, newPath) {
+		return false
+	}
+	if !caseSensitive {
+		return true
+	}
+
+	oldInfo, err := os.Lstat(oldPath)
+	if err != nil {
+		return false
+	}
+	newInfo, err := os.Lstat(newPath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(oldInfo, newInfo)
+}
+
+// stagingPath returns an unused temporary name next to path.
+func stagingPath(path string) (string, error) {
+	dir, base := filepath.Split(path)
+	for range 100 {
+		candidate := filepath.Join(dir, fmt.Sprintf(".renym-%08x-%s", rand.Uint32(), base))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a free temporary name for %s", path)
+}
+
+// rollback reverts applied renames in reverse order. It keeps going after a
+// failure so as much as possible is restored, and reports every failure.
+func rollback(applied []RenameOp, journal Journal) error {
+	var errs []error
+	var remaining []RenameOp
+	for i := len(applied) - 1; i >= 0; i-- {
+		op := applied[i]
+		// Every rollback takes back a name the batch freed
+		if err := journalRename(journal, op.NewPath, op.OldPath, true); err != nil {
+			errs = append(errs, fmt.Errorf("%s is still named %s: %w", op.OldPath, op.NewPath, err))
+			remaining = append(remaining, op)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	slices.Reverse(remaining)
+	return &RollbackError{Remaining: remaining, Err: errors.Join(errs...)}
+}
@@ -1,11 +1,14 @@
 package fs
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/MSmaili/renym/internal/common/testutils"
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
 )
 
 func TestApply(t *testing.T) {
@@ -55,6 +58,28 @@ func TestApply(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "failure rolls back completed renames",
+			existing: []string{"a.txt", "b.txt"},
+			ops: []RenameOp{
+				{OldPath: "a.txt", NewPath: "a1.txt"},
+				{OldPath: "b.txt", NewPath: "b1.txt"},
+				{OldPath: "missing.txt", NewPath: "c1.txt"},
+			},
+			test: func(t *testing.T, root string, ops []RenameOp, err error) {
+				if err == nil {
+					t.Fatalf("expected error but got success")
+				}
+				for _, op := range ops[:2] {
+					if _, err := os.Stat(op.OldPath); err != nil {
+						t.Errorf("expected rollback to restore %s", op.OldPath)
+					}
+					if _, err := os.Stat(op.NewPath); !os.IsNotExist(err) {
+						t.Errorf("renamed path still exists after rollback: %s", op.NewPath)
+					}
+				}
+			},
+		},
 		{
 			name:     "rename missing file should error",
 			existing: []string{"exists.txt"},
@@ -83,9 +108,276 @@ func TestApply(t *testing.T) {
 				}
 			}
 
-			err := Apply(opsAbs, false)
+			err := Apply(opsAbs, ApplyOptions{CaseSensitive: true})
 
 			tt.test(t, root, opsAbs, err)
 		})
 	}
 }
+
+func TestRollbackReportsFailures(t *testing.T) {
+	root := t.TempDir()
+	testutils.CreateFiles(t, root, []string{"b1.txt"})
+
+	applied := []RenameOp{
+		{OldPath: filepath.Join(root, "a.txt"), NewPath: filepath.Join(root, "gone.txt")},
+		{OldPath: filepath.Join(root, "b.txt"), NewPath: filepath.Join(root, "b1.txt")},
+	}
+
+	err := rollback(applied, nil)
+	if err == nil {
+		t.Fatalf("expected rollback error")
+	}
+	if !strings.Contains(err.Error(), "gone.txt") {
+		t.Errorf("expected failed rollback to be reported, got: %v", err)
+	}
+
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) || !errors.Is(err, ErrRollbackIncomplete) {
+		t.Fatalf("expected a RollbackError, got: %T", err)
+	}
+	assert.Len(t, rollbackErr.Remaining, 1)
+	assert.Equal(t, rollbackErr.Remaining[0], applied[0])
+
+	// The failure must not stop the remaining renames from being restored
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); err != nil {
+		t.Errorf("expected b.txt to be restored")
+	}
+}
+
+func TestApplyPermutations(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  [][2]string
+		// expected maps each final name to the content it should hold
+		expected map[string]string
+	}{
+		{
+			name:     "swap",
+			ops:      [][2]string{{"a", "b"}, {"b", "a"}},
+			expected: map[string]string{"a": "b", "b": "a"},
+		},
+		{
+			name:     "cycle",
+			ops:      [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}},
+			expected: map[string]string{"a": "c", "b": "a", "c": "b"},
+		},
+		{
+			name:     "chain_in_any_order",
+			ops:      [][2]string{{"a", "b"}, {"b", "c"}},
+			expected: map[string]string{"b": "a", "c": "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			ops := make([]RenameOp, len(tt.ops))
+			for i, op := range tt.ops {
+				if err := os.WriteFile(filepath.Join(root, op[0]), []byte(op[0]), 0644); err != nil {
+					t.Fatal(err)
+				}
+				ops[i] = RenameOp{OldPath: filepath.Join(root, op[0]), NewPath: filepath.Join(root, op[1])}
+			}
+
+			if err := Apply(ops, ApplyOptions{CaseSensitive: true}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entries, _ := os.ReadDir(root)
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("expected %d files, got %d (leftover temporary names?)", len(tt.expected), len(entries))
+			}
+			for name, content := range tt.expected {
+				data, err := os.ReadFile(filepath.Join(root, name))
+				if err != nil {
+					t.Fatalf("missing %s: %v", name, err)
+				}
+				if string(data) != content {
+					t.Errorf("%s holds %q, want %q", name, data, content)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPermutationRollback(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ops := []RenameOp{
+		{OldPath: filepath.Join(root, "a"), NewPath: filepath.Join(root, "b")},
+		{OldPath: filepath.Join(root, "b"), NewPath: filepath.Join(root, "a")},
+		{OldPath: filepath.Join(root, "missing"), NewPath: filepath.Join(root, "c")},
+	}
+
+	if err := Apply(ops, ApplyOptions{CaseSensitive: true}); err == nil {
+		t.Fatalf("expected error but got success")
+	}
+
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 2 {
+		t.Fatalf("expected only the original files to remain, got %d entries", len(entries))
+	}
+	for _, name := range []string{"a", "b"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil || string(data) != name {
+			t.Errorf("%s was not restored: %q, %v", name, data, err)
+		}
+	}
+}
+
+// recordingJournal records, by target name, which renames were synced.
+type recordingJournal struct {
+	last   string
+	synced map[string]bool
+}
+
+func (j *recordingJournal) Pending(oldPath, newPath string) error {
+	j.last = filepath.Base(newPath)
+	j.synced[j.last] = false
+	return nil
+}
+
+func (j *recordingJournal) Done() error { return nil }
+
+func (j *recordingJournal) Sync() error {
+	j.synced[j.last] = true
+	return nil
+}
+
+func TestApplySyncsJournal(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ops := []RenameOp{
+		{OldPath: filepath.Join(root, "a"), NewPath: filepath.Join(root, "b")},
+		{OldPath: filepath.Join(root, "b"), NewPath: filepath.Join(root, "a")},
+		{OldPath: filepath.Join(root, "c"), NewPath: filepath.Join(root, "d")},
+	}
+
+	journal := &recordingJournal{synced: make(map[string]bool)}
+	assert.Nil(t, Apply(ops, ApplyOptions{CaseSensitive: true, Journal: journal}))
+
+	assert.Equal(t, len(journal.synced), 4)
+	for name, synced := range journal.synced {
+		if strings.HasPrefix(name, ".renym-") {
+			assert.True(t, synced, "staging rename should be synced")
+		}
+	}
+	assert.True(t, journal.synced["a"], "rename to a vacated name should be synced")
+	assert.True(t, journal.synced["b"], "rename to a vacated name should be synced")
+	assert.False(t, journal.synced["d"], "rename to a fresh name should not be synced")
+}
+
+func TestApplyCaseOnlyRename(t *testing.T) {
+	root := t.TempDir()
+	oldPath := filepath.Join(root, "Report.TXT")
+	newPath := filepath.Join(root, "report.txt")
+
+	if err := os.WriteFile(oldPath, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Apply([]RenameOp{{OldPath: oldPath, NewPath: newPath}}, ApplyOptions{CaseSensitive: false})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 || entries[0].Name() != "report.txt" {
+		t.Fatalf("expected only report.txt, got %d entries", len(entries))
+	}
+}
+
+func TestIsCaseOnlyRename(t *testing.T) {
+	root := t.TempDir()
+	testutils.CreateFiles(t, root, []string{"Photo.JPG", "other.jpg"})
+
+	tests := []struct {
+		name          string
+		oldPath       string
+		newPath       string
+		caseSensitive bool
+		expected      bool
+	}{
+		{"case_insensitive", "Photo.JPG", "photo.jpg", false, true},
+		{"not_case_only", "Photo.JPG", "other.jpg", false, false},
+		{"unchanged", "Photo.JPG", "Photo.JPG", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := isCaseOnlyRename(filepath.Join(root, tt.oldPath), filepath.Join(root, tt.newPath), tt.caseSensitive)
+			if result != tt.expected {
+				t.Errorf("isCaseOnlyRename() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyNeverOverwrites(t *testing.T) {
+	root := t.TempDir()
+	// target.txt appeared after the plan was made
+	testutils.CreateFiles(t, root, []string{"a.txt", "b.txt", "target.txt"})
+
+	ops := []RenameOp{
+		{OldPath: filepath.Join(root, "a.txt"), NewPath: filepath.Join(root, "a1.txt")},
+		{OldPath: filepath.Join(root, "b.txt"), NewPath: filepath.Join(root, "target.txt")},
+	}
+
+	err := Apply(ops, ApplyOptions{CaseSensitive: true})
+	if !errors.Is(err, os.ErrExist) {
+		t.Fatalf("expected an already exists error, got: %v", err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt", "target.txt"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("expected %s to be left in place", name)
+		}
+	}
+}
+
+func TestRenameNoReplace(t *testing.T) {
+	testRenameNoReplace(t, renameNoReplace)
+}
+
+func testRenameNoReplace(t *testing.T, rename func(oldPath, newPath string) error) {
+	root := t.TempDir()
+	testutils.CreateFiles(t, root, []string{"a.txt", "taken.txt"})
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := rename(filepath.Join(root, "a.txt"), filepath.Join(root, "taken.txt"))
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected an already exists error, got: %v", err)
+	}
+
+	if err := rename(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")); err != nil {
+		t.Errorf("unexpected error renaming file: %v", err)
+	}
+	if err := rename(filepath.Join(root, "dir"), filepath.Join(root, "folder")); err != nil {
+		t.Errorf("unexpected error renaming directory: %v", err)
+	}
+
+	for _, name := range []string{"b.txt", "folder", "taken.txt"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("expected %s to exist", name)
+		}
+	}
+	for _, name := range []string{"a.txt", "dir"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be gone", name)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package fs
+
+import (
+	"os"
+	"time"
+)
+
+// FileIdentity fingerprints a file, so a drift check can tell whether the
+// file at a path is still the one that was renamed and was not edited since.
+type FileIdentity struct {
+	// ID is the device and file number, see FileSystemAdapter.PathIdentifier.
+	ID string
+	// Size and ModTime are only recorded for files. Renaming entries inside a
+	// directory changes its modification time, so directories are compared
+	// by ID alone.
+	Size    int64
+	ModTime time.Time
+}
+
+// Identify returns the identity of the file or directory at path.
+func Identify(adapter FileSystemAdapter, path string) (FileIdentity, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileIdentity{}, err
+	}
+
+	id, err := adapter.PathIdentifier(path)
+	if err != nil {
+		return FileIdentity{}, err
+	}
+
+	if info.IsDir() {
+		return FileIdentity{ID: id}, nil
+	}
+	return FileIdentity{ID: id, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Modified reports whether the file has the same ID as want but different
+// content metadata.
+func (f FileIdentity) Modified(want FileIdentity) bool {
+	return f.Size != want.Size || !f.ModTime.Equal(want.ModTime)
+}
@@ -0,0 +1,25 @@
+//go:build darwin
+
+package fs
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames oldPath to newPath, failing with os.ErrExist instead
+// of overwriting newPath. It uses renamex_np(RENAME_EXCL) and falls back when
+// the filesystem does not support it.
+func renameNoReplace(oldPath, newPath string) error {
+	err := unix.RenamexNp(oldPath, newPath, unix.RENAME_EXCL)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, unix.ENOTSUP), errors.Is(err, unix.EINVAL):
+		return renameNoReplaceFallback(oldPath, newPath)
+	default:
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+}
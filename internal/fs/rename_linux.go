@@ -0,0 +1,25 @@
+//go:build linux
+
+package fs
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames oldPath to newPath, failing with os.ErrExist instead
+// of overwriting newPath. It uses renameat2(RENAME_NOREPLACE) and falls back
+// when the kernel or filesystem does not support it.
+func renameNoReplace(oldPath, newPath string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, oldPath, unix.AT_FDCWD, newPath, unix.RENAME_NOREPLACE)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, unix.ENOSYS), errors.Is(err, unix.EINVAL), errors.Is(err, unix.ENOTSUP):
+		return renameNoReplaceFallback(oldPath, newPath)
+	default:
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package fs
+
+// renameNoReplace renames oldPath to newPath, failing with os.ErrExist instead
+// of overwriting newPath.
+func renameNoReplace(oldPath, newPath string) error {
+	return renameNoReplaceFallback(oldPath, newPath)
+}
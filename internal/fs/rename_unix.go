@@ -0,0 +1,40 @@
+//go:build !windows
+
+package fs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// renameNoReplaceFallback is used where the kernel has no atomic no-replace
+// rename. Files are hard-linked to the new name, which fails atomically if it
+// exists, and then unlinked from the old one. Directories and filesystems
+// without hard links fall back to checking the target right before renaming,
+// which narrows but does not close the race.
+func renameNoReplaceFallback(oldPath, newPath string) error {
+	info, err := os.Lstat(oldPath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+
+	if !info.IsDir() {
+		err := os.Link(oldPath, newPath)
+		if err == nil {
+			if err := os.Remove(oldPath); err != nil {
+				os.Remove(newPath)
+				return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+			}
+			return nil
+		}
+		if errors.Is(err, os.ErrExist) {
+			return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EEXIST}
+		}
+	}
+
+	if _, err := os.Lstat(newPath); err == nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EEXIST}
+	}
+	return os.Rename(oldPath, newPath)
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// renameNoReplace renames oldPath to newPath, failing with os.ErrExist instead
+// of overwriting newPath. MoveFileEx without MOVEFILE_REPLACE_EXISTING refuses
+// to replace an existing target.
+func renameNoReplace(oldPath, newPath string) error {
+	from, err := windows.UTF16PtrFromString(oldPath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	to, err := windows.UTF16PtrFromString(newPath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+
+	if err := windows.MoveFileEx(from, to, 0); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	return nil
+}
@@ -83,3 +83,7 @@ func TestDetectCaseSensitivity(t *testing.T) {
 		t.Fatal("detectCaseSensitivity returned a non-boolean value")
 	}
 }
+
+func TestRenameNoReplaceFallback(t *testing.T) {
+	testRenameNoReplace(t, renameNoReplaceFallback)
+}
@@ -0,0 +1,71 @@
+package history
+
+import (
+	"path/filepath"
+
+	"github.com/MSmaili/renym/internal/walker"
+)
+
+// Filter selects operations of an entry by glob patterns, with the same
+// syntax as --include. A pattern matches an operation if it matches the old
+// or new base name, or, when it contains a slash, the old or new path
+// relative to the entry's directory, where "**" matches any number of
+// directories.
+type Filter struct {
+	Only    []string
+	Exclude []string
+}
+
+// Validate reports the first malformed pattern.
+func (f Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Only...), f.Exclude...) {
+		if err := walker.ValidGlob(filepath.ToSlash(pattern)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the filter selects every operation.
+func (f Filter) IsEmpty() bool {
+	return len(f.Only) == 0 && len(f.Exclude) == 0
+}
+
+// Split returns the operations of entry the filter selects and the ones it
+// leaves, both in their recorded order.
+func (f Filter) Split(entry *Entry) (selected, rest []Operation) {
+	for _, op := range entry.Operations {
+		if f.selects(entry.Path, op) {
+			selected = append(selected, op)
+		} else {
+			rest = append(rest, op)
+		}
+	}
+	return selected, rest
+}
+
+func (f Filter) selects(dir string, op Operation) bool {
+	if len(f.Only) > 0 && !matchesAny(f.Only, dir, op) {
+		return false
+	}
+	return !matchesAny(f.Exclude, dir, op)
+}
+
+func matchesAny(patterns []string, dir string, op Operation) bool {
+	for _, pattern := range patterns {
+		for _, path := range []string{op.Old, op.New} {
+			if matchPath(filepath.ToSlash(pattern), dir, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchPath(pattern, dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		rel = path
+	}
+	return walker.MatchPattern(pattern, filepath.Base(path), filepath.ToSlash(rel))
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestFilterSplit(t *testing.T) {
+	dir := filepath.Join("home", "photos")
+	entry := &Entry{
+		Path: dir,
+		Operations: []Operation{
+			{Old: filepath.Join(dir, "IMG 1.jpg"), New: filepath.Join(dir, "img-1.jpg")},
+			{Old: filepath.Join(dir, "Notes.txt"), New: filepath.Join(dir, "notes.txt")},
+			{Old: filepath.Join(dir, "Trip", "IMG 2.jpg"), New: filepath.Join(dir, "Trip", "img-2.jpg")},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		selected []string
+	}{
+		{
+			name:     "empty_selects_all",
+			selected: []string{"img-1.jpg", "notes.txt", "img-2.jpg"},
+		},
+		{
+			name:     "only_new_name",
+			filter:   Filter{Only: []string{"*.jpg"}},
+			selected: []string{"img-1.jpg", "img-2.jpg"},
+		},
+		{
+			name:     "only_old_name",
+			filter:   Filter{Only: []string{"Notes*"}},
+			selected: []string{"notes.txt"},
+		},
+		{
+			name:     "only_relative_path",
+			filter:   Filter{Only: []string{"Trip/*"}},
+			selected: []string{"img-2.jpg"},
+		},
+		{
+			name:     "only_double_star",
+			filter:   Filter{Only: []string{"**/*.jpg"}},
+			selected: []string{"img-1.jpg", "img-2.jpg"},
+		},
+		{
+			name:     "only_double_star_directory",
+			filter:   Filter{Only: []string{"Trip/**"}},
+			selected: []string{"img-2.jpg"},
+		},
+		{
+			name:     "only_anchored_path",
+			filter:   Filter{Only: []string{"/*.txt"}},
+			selected: []string{"notes.txt"},
+		},
+		{
+			name:     "exclude",
+			filter:   Filter{Exclude: []string{"*.txt"}},
+			selected: []string{"img-1.jpg", "img-2.jpg"},
+		},
+		{
+			name:     "only_and_exclude",
+			filter:   Filter{Only: []string{"*.jpg"}, Exclude: []string{"img-2*"}},
+			selected: []string{"img-1.jpg"},
+		},
+		{
+			name:   "no_match",
+			filter: Filter{Only: []string{"*.png"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, rest := tt.filter.Split(entry)
+
+			names := make([]string, 0, len(selected))
+			for _, op := range selected {
+				names = append(names, filepath.Base(op.New))
+			}
+			assert.SliceEqual(t, names, tt.selected)
+			assert.Equal(t, len(selected)+len(rest), len(entry.Operations))
+		})
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	assert.Nil(t, Filter{Only: []string{"*.jpg"}, Exclude: []string{"a?c"}}.Validate())
+	assert.Nil(t, Filter{Only: []string{"Trip/**/*.jpg"}}.Validate())
+	assert.NotNil(t, Filter{Exclude: []string{"[a-"}}.Validate())
+	assert.NotNil(t, Filter{Only: []string{"Trip/[a-/*.jpg"}}.Validate())
+}
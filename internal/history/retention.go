@@ -0,0 +1,321 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxEntries is how many entries are kept per directory by default.
+	DefaultMaxEntries = 10
+
+	// HistoryLimitEnv overrides DefaultMaxEntries, 0 keeps every entry.
+	HistoryLimitEnv = "RENYM_HISTORY_LIMIT"
+
+	// HistoryMaxAgeEnv sets Retention.MaxAge, such as 30d or 12h.
+	HistoryMaxAgeEnv = "RENYM_HISTORY_MAX_AGE"
+
+	// HistoryMaxSizeEnv sets Retention.MaxTotalSize, such as 50MB.
+	HistoryMaxSizeEnv = "RENYM_HISTORY_MAX_SIZE"
+)
+
+// Retention limits how much history is kept.
+type Retention struct {
+	// MaxEntries is the number of entries kept per directory, 0 means no limit.
+	MaxEntries int
+	// MaxAge removes entries recorded longer ago, 0 means no limit.
+	MaxAge time.Duration
+	// MaxTotalSize caps the size in bytes of the history of all directories
+	// together, removing the oldest entries first. 0 means no limit.
+	MaxTotalSize int64
+}
+
+// RetentionFromEnv returns the default retention, adjusted by HistoryLimitEnv,
+// HistoryMaxAgeEnv and HistoryMaxSizeEnv.
+func RetentionFromEnv() (Retention, error) {
+	retention := Retention{MaxEntries: DefaultMaxEntries}
+
+	if value := os.Getenv(HistoryLimitEnv); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return Retention{}, fmt.Errorf("invalid %s '%s': must be a number of entries, 0 for no limit", HistoryLimitEnv, value)
+		}
+		retention.MaxEntries = limit
+	}
+
+	if value := os.Getenv(HistoryMaxAgeEnv); value != "" {
+		age, err := ParseAge(value)
+		if err != nil {
+			return Retention{}, fmt.Errorf("invalid %s: %w", HistoryMaxAgeEnv, err)
+		}
+		retention.MaxAge = age
+	}
+
+	if value := os.Getenv(HistoryMaxSizeEnv); value != "" {
+		size, err := ParseSize(value)
+		if err != nil {
+			return Retention{}, fmt.Errorf("invalid %s: %w", HistoryMaxSizeEnv, err)
+		}
+		retention.MaxTotalSize = size
+	}
+
+	return retention, nil
+}
+
+// ParseAge parses an age such as 90m, 12h or 30d, 0 means no limit.
+func ParseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("'%s' is not an age such as 30d or 12h", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("'%s' is not an age such as 30d or 12h", value)
+	}
+	return age, nil
+}
+
+// ParseSize parses a size in bytes such as 4096, 512K, 50MB or 1G, using
+// powers of 1024. 0 means no limit.
+func ParseSize(value string) (int64, error) {
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+
+	multiplier := int64(1)
+	for i, unit := range []string{"K", "M", "G"} {
+		if rest, ok := strings.CutSuffix(number, unit); ok {
+			number = rest
+			multiplier = 1 << (10 * (i + 1))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("'%s' is not a size such as 512K or 50MB", value)
+	}
+	return n * multiplier, nil
+}
+
+// PruneResult reports what was removed from history.
+type PruneResult struct {
+	Entries int
+	Bytes   int64
+}
+
+func (r *PruneResult) add(other PruneResult) {
+	r.Entries += other.Entries
+	r.Bytes += other.Bytes
+}
+
+// remove deletes a history file and counts it.
+func (r *PruneResult) remove(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err == nil {
+		r.Entries++
+		r.Bytes += info.Size()
+	}
+}
+
+// Prune applies retention to the history of every directory, including
+// directories that no longer exist. Folders left empty are removed.
+func (s *GlobalStore) Prune(retention Retention) (PruneResult, error) {
+	var result PruneResult
+
+	root := filepath.Join(s.configDir, historySubDir)
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		histDir := filepath.Join(root, dir.Name())
+		for _, path := range []string{histDir, filepath.Join(histDir, redoSubDir)} {
+			pruned, err := s.pruneDir(path, retention)
+			if err != nil && !os.IsNotExist(err) {
+				return result, err
+			}
+			result.add(pruned)
+		}
+	}
+
+	pruned, err := s.pruneSize(retention.MaxTotalSize, "")
+	result.add(pruned)
+	if err != nil {
+		return result, err
+	}
+
+	// Remove only succeeds on empty folders
+	for _, dir := range dirs {
+		histDir := filepath.Join(root, dir.Name())
+		os.Remove(filepath.Join(histDir, redoSubDir))
+		os.Remove(histDir)
+	}
+
+	return result, nil
+}
+
+// Clear removes every history and redo entry of dirPath.
+func (s *GlobalStore) Clear(dirPath string) (PruneResult, error) {
+	dirID, err := s.resolveDirID(dirPath)
+	if err != nil {
+		return PruneResult{}, err
+	}
+	return clearDir(s.dirHistoryPath(dirID))
+}
+
+// ClearAll removes the history of every directory.
+func (s *GlobalStore) ClearAll() (PruneResult, error) {
+	return clearDir(filepath.Join(s.configDir, historySubDir))
+}
+
+func clearDir(dir string) (PruneResult, error) {
+	files, err := historyFiles(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return PruneResult{}, nil
+		}
+		return PruneResult{}, err
+	}
+
+	var result PruneResult
+	for _, file := range files {
+		result.Entries++
+		result.Bytes += file.size
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return PruneResult{}, fmt.Errorf("failed to delete history: %w", err)
+	}
+	return result, nil
+}
+
+// cleanup applies the store's retention after an entry was written to
+// histDir.
+func (s *GlobalStore) cleanup(histDir string) error {
+	_, err := s.pruneDir(histDir, s.retention)
+	return err
+}
+
+// pruneDir applies the entry count and age limits to the entries in histDir.
+func (s *GlobalStore) pruneDir(histDir string, retention Retention) (PruneResult, error) {
+	var result PruneResult
+
+	jsonFiles, err := entryFiles(histDir)
+	if err != nil {
+		return result, err
+	}
+
+	var cutoff time.Time
+	if retention.MaxAge > 0 {
+		cutoff = time.Now().Add(-retention.MaxAge)
+	}
+
+	// Only entries that can be undone count towards the limit, so failed
+	// renames never evict a real one. They are removed along with the
+	// undoable entries older than them.
+	kept := 0
+	for i := len(jsonFiles) - 1; i >= 0; i-- {
+		filePath := filepath.Join(histDir, jsonFiles[i])
+		if retention.MaxEntries > 0 && kept >= retention.MaxEntries {
+			result.remove(filePath)
+			continue
+		}
+
+		entry, err := s.loadEntry(filePath)
+		switch {
+		case err != nil:
+			kept++
+		case entry.Timestamp.Before(cutoff):
+			result.remove(filePath)
+		case entry.Undoable():
+			kept++
+		}
+	}
+
+	return result, nil
+}
+
+// pruneSize removes the oldest history files of all directories until their
+// total size is at most maxSize. The file keep is never removed.
+func (s *GlobalStore) pruneSize(maxSize int64, keep string) (PruneResult, error) {
+	var result PruneResult
+	if maxSize <= 0 {
+		return result, nil
+	}
+
+	files, err := historyFiles(filepath.Join(s.configDir, historySubDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+
+	// Entry IDs sort by the time of the rename, unlike modification times,
+	// which change whenever an entry is rewritten
+	sort.SliceStable(files, func(i, j int) bool {
+		return filepath.Base(files[i].path) < filepath.Base(files[j].path)
+	})
+
+	for _, file := range files {
+		if total <= maxSize {
+			break
+		}
+		if file.path == keep {
+			continue
+		}
+		result.remove(file.path)
+		total -= file.size
+	}
+
+	return result, nil
+}
+
+type historyFile struct {
+	path string
+	size int64
+}
+
+// historyFiles returns every history and redo file below dir.
+func historyFiles(dir string) ([]historyFile, error) {
+	var files []historyFile
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, historyFile{path: path, size: info.Size()})
+		return nil
+	})
+	return files, err
+}
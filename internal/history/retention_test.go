@@ -0,0 +1,217 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestRetentionFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected int
+		wantErr  bool
+	}{
+		{"default", "", DefaultMaxEntries, false},
+		{"custom", "25", 25, false},
+		{"unlimited", "0", 0, false},
+		{"negative", "-1", 0, true},
+		{"not_a_number", "lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(HistoryLimitEnv, tt.value)
+			t.Setenv(HistoryMaxAgeEnv, "")
+			t.Setenv(HistoryMaxSizeEnv, "")
+
+			retention, err := RetentionFromEnv()
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, retention.MaxEntries, tt.expected)
+		})
+	}
+}
+
+func TestRetentionFromEnvLimits(t *testing.T) {
+	t.Setenv(HistoryLimitEnv, "")
+	t.Setenv(HistoryMaxAgeEnv, "30d")
+	t.Setenv(HistoryMaxSizeEnv, "50MB")
+
+	retention, err := RetentionFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, retention.MaxAge, 30*24*time.Hour)
+	assert.Equal(t, retention.MaxTotalSize, int64(50<<20))
+
+	t.Setenv(HistoryMaxAgeEnv, "soon")
+	_, err = RetentionFromEnv()
+	assert.NotNil(t, err)
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"0", 0, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"-1d", 0, true},
+		{"-2h", 0, true},
+		{"week", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			age, err := ParseAge(tt.value)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, age, tt.expected)
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		wantErr  bool
+	}{
+		{"0", 0, false},
+		{"4096", 4096, false},
+		{"512K", 512 << 10, false},
+		{"512kb", 512 << 10, false},
+		{"50MB", 50 << 20, false},
+		{"1G", 1 << 30, false},
+		{"-1M", 0, true},
+		{"big", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			size, err := ParseSize(tt.value)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, size, tt.expected)
+		})
+	}
+}
+
+func TestGlobalStorePrune(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+	store.retention = Retention{}
+
+	recent := filepath.Join(tmpDir, "recent")
+	old := filepath.Join(tmpDir, "old")
+	for _, dir := range []string{recent, old} {
+		assert.Nil(t, os.MkdirAll(dir, 0755))
+	}
+	pathID.ids[recent] = "1:1"
+	pathID.ids[old] = "1:2"
+
+	now := time.Now()
+	for i := range 3 {
+		_, err := record(store, recent, Entry{Timestamp: now.Add(-time.Duration(i) * time.Minute)})
+		assert.Nil(t, err)
+	}
+	_, err := record(store, old, Entry{Timestamp: now.AddDate(0, 0, -60)})
+	assert.Nil(t, err)
+
+	result, err := store.Prune(Retention{MaxEntries: 2, MaxAge: 30 * 24 * time.Hour})
+	assert.Nil(t, err)
+	assert.Equal(t, result.Entries, 2)
+	assert.True(t, result.Bytes > 0, "expected freed bytes to be reported")
+
+	entries, err := store.List(recent)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	// The folder of a directory without history left is removed
+	_, err = os.Stat(store.dirHistoryPath("1:2"))
+	assert.True(t, os.IsNotExist(err), "expected empty history folder to be removed")
+}
+
+func TestGlobalStorePruneSize(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+	store.retention = Retention{}
+
+	for i := range 4 {
+		_, err := record(store, tmpDir, Entry{Timestamp: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC), Command: "same-size"})
+		assert.Nil(t, err)
+	}
+
+	files, err := historyFiles(filepath.Join(tmpDir, historySubDir))
+	assert.Nil(t, err)
+	assert.Len(t, files, 4)
+
+	// Rewritten entries have newer modification times than their IDs, the
+	// oldest entry was rewritten last
+	for i, file := range files {
+		modTime := time.Date(2024, 1, len(files)-i, 0, 0, 0, 0, time.UTC)
+		assert.Nil(t, os.Chtimes(file.path, modTime, modTime))
+	}
+
+	result, err := store.Prune(Retention{MaxTotalSize: 2 * files[0].size})
+	assert.Nil(t, err)
+	assert.Equal(t, result.Entries, 2)
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, entries[1].ID, "2024-01-03_000000.000000000")
+}
+
+func TestGlobalStoreClear(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	other := filepath.Join(tmpDir, "other")
+	assert.Nil(t, os.MkdirAll(other, 0755))
+	pathID.ids[other] = "1:1"
+
+	_, err := record(store, tmpDir, Entry{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+	entry, err := store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Nil(t, store.PushRedo(entry))
+	_, err = record(store, other, Entry{Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+
+	result, err := store.Clear(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Entries, 2)
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+
+	entries, err = store.List(other)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	result, err = store.ClearAll()
+	assert.Nil(t, err)
+	assert.Equal(t, result.Entries, 1)
+
+	// Clearing again finds nothing
+	result, err = store.ClearAll()
+	assert.Nil(t, err)
+	assert.Equal(t, result.Entries, 0)
+}
@@ -3,23 +3,80 @@ package history
 import "time"
 
 type Entry struct {
+	// ID identifies the entry, it is the name of its history file.
+	ID string `json:"-"`
+
 	Version   string    `json:"version"`
 	Timestamp time.Time `json:"timestamp"`
 
 	Path  string `json:"path"`
 	DirID string `json:"dir_id"`
 
+	// UndoneFrom is set on redo entries, it is the ID of the history entry
+	// they were undone from.
+	UndoneFrom string `json:"undone_from,omitempty"`
+
 	Command string `json:"command"`
 
+	// Status is the outcome of the rename, entries written before it was
+	// recorded have none and were applied.
+	Status Status `json:"status,omitempty"`
+
 	Config     any         `json:"config"`
 	Operations []Operation `json:"operations"`
 	Skipped    []Skipped   `json:"skipped"`
 	Collisions []Collision `json:"collisions"`
 }
 
+// Status is the outcome of the rename operation an entry records.
+type Status string
+
+const (
+	// StatusPlanned is written before any rename happens. An entry still
+	// planned belongs to a rename that is running or was interrupted.
+	StatusPlanned Status = "planned"
+	// StatusApplied means every operation was renamed.
+	StatusApplied Status = "applied"
+	// StatusFailed means the batch failed and was rolled back, nothing of it
+	// is left to undo.
+	StatusFailed Status = "failed"
+	// StatusPartial means the batch failed and could not be fully rolled
+	// back. Operations lists the renames still in place.
+	StatusPartial Status = "partial"
+)
+
+// Undoable reports whether the entry records renames that are in place.
+func (e *Entry) Undoable() bool {
+	switch e.Status {
+	case "", StatusApplied, StatusPartial:
+		return true
+	}
+	return false
+}
+
 type Operation struct {
 	Old string `json:"old"`
 	New string `json:"new"`
+	// Identity fingerprints New right after the rename, so undo can detect a
+	// file that was replaced or edited since. Older entries have none.
+	Identity *Identity `json:"identity,omitempty"`
+}
+
+// Identity is the device and file number of a renamed file, plus its size and
+// modification time for regular files.
+type Identity struct {
+	ID      string    `json:"id"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mtime,omitzero"`
+}
+
+// matches reports whether other identifies the same file by its device and
+// file number, even if it was edited since. A nil identity matches nothing.
+func (i *Identity) matches(other *Identity) bool {
+	if i == nil || other == nil {
+		return false
+	}
+	return i.ID != "" && i.ID == other.ID
 }
 
 type Skipped struct {
@@ -2,21 +2,31 @@ package history
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/MSmaili/renym/internal/fs"
 )
 
 const (
-	historySubDir    = "history"
-	maxEntriesPerDir = 2
+	historySubDir = "history"
+	redoSubDir    = "redo"
+
+	// entryIDLayout names history and redo files. Entries recorded before
+	// IDs had nanoseconds use legacyEntryIDLayout.
+	entryIDLayout       = "2006-01-02_150405.000000000"
+	legacyEntryIDLayout = "2006-01-02_150405"
 )
 
 type GlobalStore struct {
 	configDir string
 	pathID    PathIdentifier
+	retention Retention
 }
 
 func NewGlobalStore(pathID PathIdentifier) (*GlobalStore, error) {
@@ -25,11 +35,17 @@ func NewGlobalStore(pathID PathIdentifier) (*GlobalStore, error) {
 		return nil, fmt.Errorf("failed to get config dir: %w", err)
 	}
 
+	retention, err := RetentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	rnmConfigDir := filepath.Join(configDir, "renym")
 
 	return &GlobalStore{
 		configDir: rnmConfigDir,
 		pathID:    pathID,
+		retention: retention,
 	}, nil
 }
 
@@ -42,56 +58,123 @@ func (s *GlobalStore) dirHistoryPath(dirID string) string {
 	return filepath.Join(s.configDir, historySubDir, sanitizeDirID(dirID))
 }
 
-func (s *GlobalStore) Save(dirPath string, entry Entry) (string, error) {
+// Begin saves entry as planned before its renames run, filling in its ID and
+// directory. Finish records the outcome once the renames ran.
+func (s *GlobalStore) Begin(dirPath string, entry *Entry) error {
 	dirID, err := s.resolveDirID(dirPath)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	absPath, _ := resolveAbsolutePath(dirPath)
 
 	entry.Path = absPath
 	entry.DirID = dirID
+	entry.Status = StatusPlanned
 
 	histDir := s.dirHistoryPath(dirID)
 	if err := os.MkdirAll(histDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create history dir: %w", err)
+		return fmt.Errorf("failed to create history dir: %w", err)
 	}
 
-	fileName := entry.Timestamp.Format("2006-01-02_150405") + ".json"
-	filePath := filepath.Join(histDir, fileName)
-
-	data, err := json.MarshalIndent(entry, "", "  ")
+	id, err := createEntry(histDir, entry.Timestamp, entry)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal entry: %w", err)
+		return err
 	}
+	entry.ID = id
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write history: %w", err)
+	return nil
+}
+
+// Finish records the outcome of a rename saved as planned, see StatusPlanned.
+func (s *GlobalStore) Finish(entry *Entry, status Status) error {
+	entry.Status = status
+	if err := s.Update(entry); err != nil {
+		return err
+	}
+
+	if entry.Undoable() {
+		histDir := s.dirHistoryPath(entry.DirID)
+		s.settle(histDir, filepath.Join(histDir, entry.ID+".json"))
+	}
+	return nil
+}
+
+// settle runs after a rename was applied in histDir and recorded in filePath.
+func (s *GlobalStore) settle(histDir, filePath string) {
+	// A new operation makes the undone ones impossible to redo reliably
+	if err := os.RemoveAll(filepath.Join(histDir, redoSubDir)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: clearing redo history failed: %v\n", err)
 	}
 
 	if err := s.cleanup(histDir); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: cleanup failed: %v\n", err)
 	}
 
-	return fileName, nil
+	if _, err := s.pruneSize(s.retention.MaxTotalSize, filePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cleanup failed: %v\n", err)
+	}
 }
 
-func (s *GlobalStore) Latest(dirPath string) (*Entry, error) {
-	dirID, err := s.resolveDirID(dirPath)
+// createEntry writes entry to a new file in dir, named by t. It never
+// replaces another entry: if the name is taken, t is moved on by a
+// nanosecond. It returns the ID, the file name without extension.
+func createEntry(dir string, t time.Time, entry *Entry) (string, error) {
+	data, err := json.MarshalIndent(entry, "", "  ")
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to marshal entry: %w", err)
 	}
 
-	histDir := s.dirHistoryPath(dirID)
+	for range 100 {
+		id := t.Format(entryIDLayout)
+		file, err := os.OpenFile(filepath.Join(dir, id+".json"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			t = t.Add(time.Nanosecond)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write history: %w", err)
+		}
+
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("failed to write history: %w", err)
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("failed to write history: no free entry name in %s", dir)
+}
+
+func writeEntry(filePath string, entry *Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal entry: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
 
-	latest, err := s.latestFile(histDir)
+	return nil
+}
+
+// Latest returns the newest entry of dirPath that can be undone.
+func (s *GlobalStore) Latest(dirPath string) (*Entry, error) {
+	entries, err := s.List(dirPath)
 	if err != nil {
 		return nil, err
 	}
-	filePath := filepath.Join(histDir, latest)
 
-	return s.loadEntry(filePath)
+	for _, entry := range entries {
+		if entry.Undoable() {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("No history found for directory")
 }
 
 func (s *GlobalStore) loadEntry(path string) (*Entry, error) {
@@ -105,53 +188,223 @@ func (s *GlobalStore) loadEntry(path string) (*Entry, error) {
 		return nil, fmt.Errorf("failed to parse history: %w", err)
 	}
 
+	entry.ID = strings.TrimSuffix(filepath.Base(path), ".json")
 	sortOperationsByDepth(entry.Operations)
 	return &entry, nil
 }
 
-func (s *GlobalStore) latestFile(histDir string) (string, error) {
-	entries, err := os.ReadDir(histDir)
+// List returns every history entry recorded for dirPath, newest first.
+func (s *GlobalStore) List(dirPath string) ([]*Entry, error) {
+	dirID, err := s.resolveDirID(dirPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read history directory: %w", err)
+		return nil, err
 	}
 
-	var latest string
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
+	histDir := s.dirHistoryPath(dirID)
+
+	files, err := entryFiles(histDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
 
-		name := e.Name()
-		if !strings.HasSuffix(name, ".json") {
+	entries := make([]*Entry, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		entry, err := s.loadEntry(filepath.Join(histDir, files[i]))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Find returns the entry with the given ID, searching the history of every directory.
+func (s *GlobalStore) Find(id string) (*Entry, error) {
+	dirs, err := os.ReadDir(filepath.Join(s.configDir, historySubDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var matches []string
+	for _, dir := range dirs {
+		if !dir.IsDir() {
 			continue
 		}
+		path := filepath.Join(s.configDir, historySubDir, dir.Name(), id+".json")
+		if _, err := os.Stat(path); err == nil {
+			matches = append(matches, path)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("history entry '%s' not found", id)
+	case 1:
+		return s.loadEntry(matches[0])
+	}
 
-		if latest == "" || name > latest {
-			latest = name
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		entry, err := s.loadEntry(match)
+		if err != nil {
+			return nil, err
 		}
+		paths = append(paths, entry.Path)
+	}
+	return nil, fmt.Errorf("history entry '%s' exists for several directories: %s", id, strings.Join(paths, ", "))
+}
+
+// entryFiles returns the names of the history files in histDir, oldest first.
+func entryFiles(histDir string) ([]string, error) {
+	entries, err := os.ReadDir(histDir)
+	if err != nil {
+		return nil, err
 	}
 
-	if latest == "" {
-		return "", fmt.Errorf("No history found for directory")
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, e.Name())
+		}
 	}
+	sort.Strings(files)
 
-	return latest, nil
+	return files, nil
 }
 
-func (s *GlobalStore) Delete(dirPath string) error {
-	dirID, err := s.resolveDirID(dirPath)
+// Update rewrites an existing entry, e.g. after some of its operations were undone.
+func (s *GlobalStore) Update(entry *Entry) error {
+	if entry.ID == "" || entry.DirID == "" {
+		return fmt.Errorf("history entry has no ID")
+	}
+
+	return writeEntry(filepath.Join(s.dirHistoryPath(entry.DirID), entry.ID+".json"), entry)
+}
+
+// FindByTarget returns the newest entry that renamed a file to filePath, along
+// with the index of that operation. The history of every directory from the
+// file's parent up to the root is searched, so a file renamed by a recursive
+// run in an ancestor directory is found too.
+func (s *GlobalStore) FindByTarget(filePath string) (*Entry, int, error) {
+	target, err := filepath.Abs(filePath)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
-	histDir := s.dirHistoryPath(dirID)
+	for dir := filepath.Dir(target); ; dir = filepath.Dir(dir) {
+		entries, err := s.List(dir)
+		if err == nil {
+			for _, entry := range entries {
+				if !entry.Undoable() {
+					continue
+				}
+				for i, newPath := range finalPaths(entry) {
+					if newPath, err := filepath.Abs(newPath); err == nil && newPath == target {
+						return entry, i, nil
+					}
+				}
+			}
+		}
 
-	latest, err := s.latestFile(histDir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return nil, 0, fmt.Errorf("no history found for %s", filePath)
+}
+
+// Match is a rename found by Which.
+type Match struct {
+	Entry     *Entry
+	Operation Operation
+	// ByIdentity is set when the rename was found by the file's identity
+	// rather than its path, because the file was moved since.
+	ByIdentity bool
+}
+
+// Which returns the renames that led to filePath across the history of every
+// directory, newest first, so the last match holds the original name. A
+// rename matches by its new path or, if identity is given, by the identity
+// recorded for it, which finds files that were moved since.
+func (s *GlobalStore) Which(filePath string, identity *Identity) ([]Match, error) {
+	target, err := filepath.Abs(filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	filePath := filepath.Join(histDir, latest)
 
+	entries, err := s.allEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, entry := range entries {
+		if !entry.Undoable() {
+			continue
+		}
+		for i, newPath := range finalPaths(entry) {
+			op := entry.Operations[i]
+			byPath := newPath == target
+			if !byPath && !identity.matches(op.Identity) {
+				continue
+			}
+			matches = append(matches, Match{Entry: entry, Operation: op, ByIdentity: !byPath})
+			target = op.Old
+			break
+		}
+	}
+
+	return matches, nil
+}
+
+// allEntries returns the entries of every directory, newest first.
+func (s *GlobalStore) allEntries() ([]*Entry, error) {
+	root := filepath.Join(s.configDir, historySubDir)
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var entries []*Entry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		histDir := filepath.Join(root, dir.Name())
+		files, err := entryFiles(histDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history directory: %w", err)
+		}
+		for _, file := range files {
+			entry, err := s.loadEntry(filepath.Join(histDir, file))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+// DeleteEntry removes a single entry, e.g. one returned by List or Find.
+func (s *GlobalStore) DeleteEntry(entry *Entry) error {
+	if entry.ID == "" || entry.DirID == "" {
+		return fmt.Errorf("history entry has no ID")
+	}
+
+	filePath := filepath.Join(s.dirHistoryPath(entry.DirID), entry.ID+".json")
 	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete history file: %w", err)
 	}
@@ -159,31 +412,105 @@ func (s *GlobalStore) Delete(dirPath string) error {
 	return nil
 }
 
-func (s *GlobalStore) cleanup(histDir string) error {
-	entries, err := os.ReadDir(histDir)
-	if err != nil {
+// PushRedo puts an undone entry on the redo stack of its directory.
+func (s *GlobalStore) PushRedo(entry *Entry) error {
+	if entry.DirID == "" {
+		return fmt.Errorf("history entry has no directory")
+	}
+
+	redoDir := filepath.Join(s.dirHistoryPath(entry.DirID), redoSubDir)
+	if err := os.MkdirAll(redoDir, 0755); err != nil {
+		return fmt.Errorf("failed to create redo dir: %w", err)
+	}
+
+	// Named by undo time, so the stack pops in reverse order of undoing
+	redo := *entry
+	redo.UndoneFrom = entry.ID
+	if _, err := createEntry(redoDir, time.Now(), &redo); err != nil {
 		return err
 	}
 
-	var jsonFiles []string
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
-			jsonFiles = append(jsonFiles, e.Name())
+	return s.cleanup(redoDir)
+}
+
+// ListRedo returns the redo stack of dirPath, most recently undone first.
+func (s *GlobalStore) ListRedo(dirPath string) ([]*Entry, error) {
+	dirID, err := s.resolveDirID(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	redoDir := filepath.Join(s.dirHistoryPath(dirID), redoSubDir)
+
+	files, err := entryFiles(redoDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read redo directory: %w", err)
+	}
+
+	entries := make([]*Entry, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		entry, err := s.loadEntry(filepath.Join(redoDir, files[i]))
+		if err != nil {
+			return nil, err
 		}
+		entries = append(entries, entry)
 	}
 
-	if len(jsonFiles) <= maxEntriesPerDir {
-		return nil
+	return entries, nil
+}
+
+// Restore moves a redone entry from the redo stack back into history. If
+// the entry was only partly undone, its operations are merged back into the
+// remaining part.
+func (s *GlobalStore) Restore(entry *Entry) error {
+	if entry.ID == "" || entry.DirID == "" {
+		return fmt.Errorf("history entry has no ID")
 	}
 
-	sort.Strings(jsonFiles)
+	histDir := s.dirHistoryPath(entry.DirID)
+	redoPath := filepath.Join(histDir, redoSubDir, entry.ID+".json")
+	filePath := s.originPath(entry)
 
-	toRemove := len(jsonFiles) - maxEntriesPerDir
-	for i := range toRemove {
-		os.Remove(filepath.Join(histDir, jsonFiles[i]))
+	restored := *entry
+	restored.UndoneFrom = ""
+	if remaining, err := s.loadEntry(filePath); err == nil {
+		restored = *remaining
+		restored.Operations = append(restored.Operations, entry.Operations...)
 	}
 
-	return nil
+	if err := writeEntry(filePath, &restored); err != nil {
+		return err
+	}
+
+	if err := os.Remove(redoPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete redo file: %w", err)
+	}
+
+	return s.cleanup(histDir)
+}
+
+// Origin returns the part of the history entry a redo entry was undone from
+// that is still in history, or nil if all of it was undone.
+func (s *GlobalStore) Origin(entry *Entry) (*Entry, error) {
+	origin, err := s.loadEntry(s.originPath(entry))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return origin, err
+}
+
+// originPath returns the history file a redo entry was undone from. Redo
+// entries written before UndoneFrom was recorded were named by their
+// timestamp.
+func (s *GlobalStore) originPath(entry *Entry) string {
+	id := entry.UndoneFrom
+	if id == "" {
+		id = entry.Timestamp.Format(legacyEntryIDLayout)
+	}
+	return filepath.Join(s.dirHistoryPath(entry.DirID), id+".json")
 }
 
 func resolveAbsolutePath(dirPath string) (string, error) {
@@ -226,6 +553,25 @@ func resolveDir(path string) (string, error) {
 	return path, nil
 }
 
+// finalPaths returns where the New path of each operation of entry is once
+// the whole entry ran. A file renamed inside a directory renamed by the same
+// entry is recorded under the directory's old name.
+func finalPaths(entry *Entry) []string {
+	// Loaded entries are sorted shallowest first, their renames ran deepest
+	// first, children before their parents
+	ops := make([]fs.RenameOp, len(entry.Operations))
+	for i, op := range entry.Operations {
+		ops[len(ops)-1-i] = fs.RenameOp{OldPath: op.Old, NewPath: op.New}
+	}
+
+	index := fs.NewRenameIndex(ops)
+	paths := make([]string, len(entry.Operations))
+	for i, op := range entry.Operations {
+		paths[i] = index.Relocate(op.New)
+	}
+	return paths
+}
+
 // sortOperationsByDepth sorts operations by path depth (top-level first, deeper paths later).
 func sortOperationsByDepth(ops []Operation) {
 	type opWithDepth struct {
@@ -2,6 +2,7 @@ package history
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -32,10 +33,28 @@ func newTestStore(t *testing.T, pathID PathIdentifier) (*GlobalStore, string) {
 	store := &GlobalStore{
 		configDir: tmpDir,
 		pathID:    pathID,
+		retention: Retention{MaxEntries: 2},
 	}
 	return store, tmpDir
 }
 
+// record saves entry the way a rename does, through Begin and Finish with
+// the entry's status, or applied if it has none.
+func record(store *GlobalStore, dirPath string, entry Entry) (*Entry, error) {
+	status := entry.Status
+	if status == "" {
+		status = StatusApplied
+	}
+
+	if err := store.Begin(dirPath, &entry); err != nil {
+		return nil, err
+	}
+	if status == StatusPlanned {
+		return &entry, nil
+	}
+	return &entry, store.Finish(&entry, status)
+}
+
 func TestSanitizeDirID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -150,7 +169,7 @@ func TestSortOperationsByDepth(t *testing.T) {
 	}
 }
 
-func TestGlobalStoreSave(t *testing.T) {
+func TestGlobalStoreBegin(t *testing.T) {
 	tests := []struct {
 		name        string
 		dirPath     string
@@ -215,7 +234,7 @@ func TestGlobalStoreSave(t *testing.T) {
 				dirPath = tmpDir
 			}
 
-			fileName, err := store.Save(dirPath, tt.entry)
+			entry, err := record(store, dirPath, tt.entry)
 
 			if tt.wantErr {
 				assert.NotNil(t, err)
@@ -223,7 +242,8 @@ func TestGlobalStoreSave(t *testing.T) {
 			}
 
 			assert.Nil(t, err)
-			assert.NotEqual(t, fileName, "")
+			assert.NotEqual(t, entry.ID, "")
+			fileName := entry.ID + ".json"
 
 			absPath, _ := filepath.Abs(dirPath)
 			dirID, _ := pathID.PathIdentifier(absPath)
@@ -265,7 +285,7 @@ func TestGlobalStoreLatest(t *testing.T) {
 						{Old: "old.txt", New: "new.txt"},
 					},
 				}
-				_, err := store.Save(tmpDir, entry)
+				_, err := record(store, tmpDir, entry)
 				assert.Nil(t, err)
 				return tmpDir
 			},
@@ -283,7 +303,7 @@ func TestGlobalStoreLatest(t *testing.T) {
 					Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 					Command:   "first-entry",
 				}
-				_, err := store.Save(tmpDir, entry1)
+				_, err := record(store, tmpDir, entry1)
 				assert.Nil(t, err)
 
 				entry2 := Entry{
@@ -291,7 +311,7 @@ func TestGlobalStoreLatest(t *testing.T) {
 					Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
 					Command:   "second-entry",
 				}
-				_, err = store.Save(tmpDir, entry2)
+				_, err = record(store, tmpDir, entry2)
 				assert.Nil(t, err)
 
 				return tmpDir
@@ -326,7 +346,7 @@ func TestGlobalStoreLatest(t *testing.T) {
 						{Old: "a/b", New: "4"},
 					},
 				}
-				_, err := store.Save(tmpDir, entry)
+				_, err := record(store, tmpDir, entry)
 				assert.Nil(t, err)
 				return tmpDir
 			},
@@ -366,7 +386,7 @@ func TestGlobalStoreLatest(t *testing.T) {
 	}
 }
 
-func TestGlobalStoreDelete(t *testing.T) {
+func TestGlobalStoreDeleteLatest(t *testing.T) {
 	tests := []struct {
 		name     string
 		setup    func(t *testing.T, store *GlobalStore, tmpDir string) string
@@ -381,7 +401,7 @@ func TestGlobalStoreDelete(t *testing.T) {
 					Timestamp: time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC),
 					Command:   "to-delete",
 				}
-				_, err := store.Save(tmpDir, entry)
+				_, err := record(store, tmpDir, entry)
 				assert.Nil(t, err)
 				return tmpDir
 			},
@@ -399,7 +419,7 @@ func TestGlobalStoreDelete(t *testing.T) {
 					Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 					Command:   "older-entry",
 				}
-				_, err := store.Save(tmpDir, entry1)
+				_, err := record(store, tmpDir, entry1)
 				assert.Nil(t, err)
 
 				entry2 := Entry{
@@ -407,7 +427,7 @@ func TestGlobalStoreDelete(t *testing.T) {
 					Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
 					Command:   "newer-entry",
 				}
-				_, err = store.Save(tmpDir, entry2)
+				_, err = record(store, tmpDir, entry2)
 				assert.Nil(t, err)
 
 				return tmpDir
@@ -439,7 +459,10 @@ func TestGlobalStoreDelete(t *testing.T) {
 
 			dirPath := tt.setup(t, store, tmpDir)
 
-			err := store.Delete(dirPath)
+			entry, err := store.Latest(dirPath)
+			if err == nil {
+				err = store.DeleteEntry(entry)
+			}
 
 			if tt.wantErr {
 				assert.NotNil(t, err)
@@ -458,35 +481,53 @@ func TestGlobalStoreDelete(t *testing.T) {
 func TestGlobalStoreCleanup(t *testing.T) {
 	tests := []struct {
 		name          string
+		maxEntries    int
 		numEntries    int
 		expectedAfter int
 	}{
 		{
 			name:          "no cleanup needed with 1 entry",
+			maxEntries:    2,
 			numEntries:    1,
 			expectedAfter: 1,
 		},
 		{
 			name:          "no cleanup needed with 2 entries",
+			maxEntries:    2,
 			numEntries:    2,
 			expectedAfter: 2,
 		},
 		{
 			name:          "cleanup removes oldest when 3 entries",
+			maxEntries:    2,
 			numEntries:    3,
 			expectedAfter: 2,
 		},
 		{
 			name:          "cleanup removes oldest when 5 entries",
+			maxEntries:    2,
 			numEntries:    5,
 			expectedAfter: 2,
 		},
+		{
+			name:          "custom limit",
+			maxEntries:    4,
+			numEntries:    6,
+			expectedAfter: 4,
+		},
+		{
+			name:          "zero keeps every entry",
+			maxEntries:    0,
+			numEntries:    6,
+			expectedAfter: 6,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			pathID := &mockPathIdentifier{ids: make(map[string]string)}
 			store, tmpDir := newTestStore(t, pathID)
+			store.retention = Retention{MaxEntries: tt.maxEntries}
 
 			for i := range tt.numEntries {
 				entry := Entry{
@@ -494,7 +535,7 @@ func TestGlobalStoreCleanup(t *testing.T) {
 					Timestamp: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC),
 					Command:   "test-entry",
 				}
-				_, err := store.Save(tmpDir, entry)
+				_, err := record(store, tmpDir, entry)
 				assert.Nil(t, err)
 			}
 
@@ -636,3 +677,449 @@ func TestGlobalStoreLoadEntry(t *testing.T) {
 		})
 	}
 }
+
+func TestGlobalStoreList(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+
+	for i, command := range []string{"first", "second"} {
+		_, err := record(store, tmpDir, Entry{
+			Timestamp: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC),
+			Command:   command,
+		})
+		assert.Nil(t, err)
+	}
+
+	entries, err = store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, entries[0].Command, "second")
+	assert.Equal(t, entries[0].ID, "2024-01-02_000000.000000000")
+	assert.Equal(t, entries[1].Command, "first")
+}
+
+func TestGlobalStoreSameTimestamp(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	timestamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	applied := &Entry{Timestamp: timestamp, Command: "snake"}
+	assert.Nil(t, store.Begin(tmpDir, applied))
+	assert.Nil(t, store.Finish(applied, StatusApplied))
+
+	// A rename planned in the same instant must not replace the applied one
+	planned := &Entry{Timestamp: timestamp, Command: "kebab"}
+	assert.Nil(t, store.Begin(tmpDir, planned))
+	assert.True(t, planned.ID != applied.ID, "expected a new ID for an entry with the same timestamp")
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	latest, err := store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, latest.Command, "snake")
+}
+
+func TestGlobalStoreLegacyRedoOrigin(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	// Entries used to be named by their timestamp in seconds, and redo
+	// entries did not record which entry they were undone from
+	entry := &Entry{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Command: "snake"}
+	assert.Nil(t, store.Begin(tmpDir, entry))
+	histDir := store.dirHistoryPath(entry.DirID)
+	assert.Nil(t, os.Rename(filepath.Join(histDir, entry.ID+".json"), filepath.Join(histDir, "2024-01-01_000000.json")))
+
+	origin, err := store.Origin(&Entry{Timestamp: entry.Timestamp, DirID: entry.DirID})
+	assert.Nil(t, err)
+	assert.NotNil(t, origin)
+	assert.Equal(t, origin.Command, "snake")
+}
+
+func TestGlobalStoreFind(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	dirA := filepath.Join(tmpDir, "a")
+	dirB := filepath.Join(tmpDir, "b")
+	pathID.ids[dirA] = "1:1"
+	pathID.ids[dirB] = "1:2"
+	assert.Nil(t, os.MkdirAll(dirA, 0755))
+	assert.Nil(t, os.MkdirAll(dirB, 0755))
+
+	_, err := record(store, dirA, Entry{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Command: "in-a"})
+	assert.Nil(t, err)
+	_, err = record(store, dirB, Entry{Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Command: "in-b"})
+	assert.Nil(t, err)
+	_, err = record(store, dirB, Entry{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Command: "also-in-b"})
+	assert.Nil(t, err)
+
+	entry, err := store.Find("2024-01-02_000000.000000000")
+	assert.Nil(t, err)
+	assert.Equal(t, entry.Command, "in-b")
+	assert.Equal(t, entry.ID, "2024-01-02_000000.000000000")
+
+	_, err = store.Find("2024-01-01_000000.000000000")
+	assert.NotNil(t, err)
+
+	_, err = store.Find("2023-01-01_000000.000000000")
+	assert.NotNil(t, err)
+}
+
+func TestGlobalStoreDeleteEntry(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	for i, command := range []string{"first", "second"} {
+		_, err := record(store, tmpDir, Entry{
+			Timestamp: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC),
+			Command:   command,
+		})
+		assert.Nil(t, err)
+	}
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	// Deleting an older entry keeps the newer one
+	assert.Nil(t, store.DeleteEntry(entries[1]))
+
+	entries, err = store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, entries[0].Command, "second")
+
+	assert.NotNil(t, store.DeleteEntry(&Entry{}))
+}
+
+func TestGlobalStoreFindByTarget(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	root := filepath.Join(tmpDir, "project")
+	sub := filepath.Join(root, "sub")
+	assert.Nil(t, os.MkdirAll(sub, 0755))
+	pathID.ids[root] = "1:1"
+	pathID.ids[sub] = "1:2"
+
+	_, err := record(store, root, Entry{
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Command:   "recursive",
+		Operations: []Operation{
+			{Old: filepath.Join(root, "A.txt"), New: filepath.Join(root, "a.txt")},
+			{Old: filepath.Join(sub, "B.txt"), New: filepath.Join(sub, "b.txt")},
+		},
+	})
+	assert.Nil(t, err)
+
+	// Found in the history of an ancestor directory
+	entry, index, err := store.FindByTarget(filepath.Join(sub, "b.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, entry.Command, "recursive")
+	assert.Equal(t, entry.Operations[index].Old, filepath.Join(sub, "B.txt"))
+
+	// The newest entry wins
+	_, err = record(store, sub, Entry{
+		Timestamp:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Command:    "in-sub",
+		Operations: []Operation{{Old: filepath.Join(sub, "c.txt"), New: filepath.Join(sub, "b.txt")}},
+	})
+	assert.Nil(t, err)
+
+	entry, _, err = store.FindByTarget(filepath.Join(sub, "b.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, entry.Command, "in-sub")
+
+	_, _, err = store.FindByTarget(filepath.Join(sub, "unknown.txt"))
+	assert.NotNil(t, err)
+
+	// A file inside a directory renamed in the same run is recorded under the
+	// directory's old name
+	_, err = record(store, root, Entry{
+		Timestamp: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		Command:   "with-dirs",
+		Operations: []Operation{
+			{Old: filepath.Join(root, "MyDir", "MyFile.txt"), New: filepath.Join(root, "MyDir", "my_file.txt")},
+			{Old: filepath.Join(root, "MyDir"), New: filepath.Join(root, "my_dir")},
+		},
+	})
+	assert.Nil(t, err)
+
+	entry, index, err = store.FindByTarget(filepath.Join(root, "my_dir", "my_file.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, entry.Command, "with-dirs")
+	assert.Equal(t, entry.Operations[index].Old, filepath.Join(root, "MyDir", "MyFile.txt"))
+}
+
+func TestGlobalStoreUpdate(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	_, err := record(store, tmpDir, Entry{
+		Timestamp:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Operations: []Operation{{Old: "a", New: "b"}, {Old: "c", New: "d"}},
+	})
+	assert.Nil(t, err)
+
+	entry, err := store.Latest(tmpDir)
+	assert.Nil(t, err)
+
+	entry.Operations = entry.Operations[:1]
+	assert.Nil(t, store.Update(entry))
+
+	entry, err = store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entry.Operations, 1)
+
+	assert.NotNil(t, store.Update(&Entry{}))
+}
+
+func TestGlobalStoreRedo(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	_, err := record(store, tmpDir, Entry{
+		Timestamp:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Command:    "snake",
+		Operations: []Operation{{Old: "A B", New: "a_b"}, {Old: "C D", New: "c_d"}},
+	})
+	assert.Nil(t, err)
+
+	// Undo one operation only, the rest stays in history
+	entry, err := store.Latest(tmpDir)
+	assert.Nil(t, err)
+
+	single := *entry
+	single.Operations = entry.Operations[:1]
+	assert.Nil(t, store.PushRedo(&single))
+
+	entry.Operations = entry.Operations[1:]
+	assert.Nil(t, store.Update(entry))
+
+	redo, err := store.ListRedo(tmpDir)
+	assert.Nil(t, err)
+	origin, err := store.Origin(redo[0])
+	assert.Nil(t, err)
+	assert.Len(t, origin.Operations, 1)
+	assert.Equal(t, origin.Operations[0].Old, "C D")
+
+	// Undo the remaining operation
+	entry, err = store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Nil(t, store.PushRedo(entry))
+	assert.Nil(t, store.DeleteEntry(entry))
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+
+	redo, err = store.ListRedo(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, redo, 2)
+
+	origin, err = store.Origin(redo[0])
+	assert.Nil(t, err)
+	assert.True(t, origin == nil, "expected no origin once every rename was undone")
+	assert.Equal(t, redo[0].Operations[0].Old, "C D")
+
+	// Redo both, merging them back into one entry
+	assert.Nil(t, store.Restore(redo[0]))
+	assert.Nil(t, store.Restore(redo[1]))
+
+	redo, err = store.ListRedo(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, redo, 0)
+
+	entry, err = store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, entry.Command, "snake")
+	assert.Len(t, entry.Operations, 2)
+}
+
+func TestGlobalStoreSaveClearsRedo(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	_, err := record(store, tmpDir, Entry{Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+
+	entry, err := store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Nil(t, store.PushRedo(entry))
+	assert.Nil(t, store.DeleteEntry(entry))
+
+	_, err = record(store, tmpDir, Entry{Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+
+	redo, err := store.ListRedo(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, redo, 0)
+}
+
+func TestGlobalStoreStatus(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+
+	save := func(minute int, status Status) *Entry {
+		_, err := record(store, tmpDir, Entry{
+			Timestamp:  time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC),
+			Command:    fmt.Sprintf("op%d", minute),
+			Status:     status,
+			Operations: []Operation{{Old: "a", New: "b"}},
+		})
+		assert.Nil(t, err)
+
+		entries, err := store.List(tmpDir)
+		assert.Nil(t, err)
+		return entries[0]
+	}
+
+	save(1, StatusApplied)
+	save(2, StatusApplied)
+
+	// A planned entry is not undoable until it is finished
+	planned := &Entry{Timestamp: time.Date(2024, 1, 1, 0, 3, 0, 0, time.UTC), Command: "op3"}
+	assert.Nil(t, store.Begin(tmpDir, planned))
+	assert.Equal(t, planned.ID, "2024-01-01_000300.000000000")
+	latest, err := store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, latest.Command, "op2")
+
+	// Failed renames do not count towards the limit of 2
+	assert.Nil(t, store.Finish(planned, StatusFailed))
+	save(4, StatusFailed)
+
+	entries, err := store.List(tmpDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 4)
+
+	latest, err = store.Latest(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, latest.Command, "op2")
+
+	// Applying evicts the oldest undoable entry and the failed ones older than the kept ones
+	planned = save(5, StatusPlanned)
+	assert.Nil(t, store.Finish(planned, StatusApplied))
+
+	entries, err = store.List(tmpDir)
+	assert.Nil(t, err)
+	commands := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		commands = append(commands, entry.Command)
+	}
+	assert.SliceEqual(t, commands, []string{"op5", "op4", "op3", "op2"})
+	assert.Equal(t, entries[0].Status, StatusApplied)
+}
+
+func TestGlobalStoreWhich(t *testing.T) {
+	pathID := &mockPathIdentifier{ids: make(map[string]string)}
+	store, tmpDir := newTestStore(t, pathID)
+	store.retention = Retention{}
+
+	photos := filepath.Join(tmpDir, "photos")
+	docs := filepath.Join(tmpDir, "docs")
+	for i, dir := range []string{photos, docs} {
+		assert.Nil(t, os.MkdirAll(dir, 0755))
+		pathID.ids[dir] = fmt.Sprintf("1:%d", i+1)
+	}
+
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	identity := &Identity{ID: "9:9", Size: 3, ModTime: modTime}
+
+	save := func(dir string, day int, status Status, op Operation) {
+		_, err := record(store, dir, Entry{
+			Timestamp:  time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			Command:    fmt.Sprintf("day%d", day),
+			Status:     status,
+			Operations: []Operation{op},
+		})
+		assert.Nil(t, err)
+	}
+
+	save(photos, 1, StatusApplied, Operation{Old: filepath.Join(photos, "IMG 1.jpg"), New: filepath.Join(photos, "img_1.jpg")})
+	save(photos, 2, StatusApplied, Operation{Old: filepath.Join(photos, "img_1.jpg"), New: filepath.Join(photos, "beach.jpg"), Identity: identity})
+	save(photos, 3, StatusFailed, Operation{Old: filepath.Join(photos, "beach.jpg"), New: filepath.Join(photos, "failed.jpg")})
+	save(docs, 4, StatusApplied, Operation{Old: filepath.Join(docs, "A.txt"), New: filepath.Join(docs, "a.txt")})
+	_, err := record(store, docs, Entry{
+		Timestamp: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		Command:   "day5",
+		Operations: []Operation{
+			{Old: filepath.Join(docs, "MyDir", "MyFile.txt"), New: filepath.Join(docs, "MyDir", "my_file.txt"), Identity: &Identity{ID: "7:7"}},
+			{Old: filepath.Join(docs, "MyDir"), New: filepath.Join(docs, "my_dir")},
+		},
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name       string
+		path       string
+		identity   *Identity
+		commands   []string
+		byIdentity bool
+	}{
+		{
+			name:     "follows_renames_back_to_the_original",
+			path:     filepath.Join(photos, "beach.jpg"),
+			commands: []string{"day2", "day1"},
+		},
+		{
+			name:     "intermediate_name",
+			path:     filepath.Join(photos, "img_1.jpg"),
+			commands: []string{"day1"},
+		},
+		{
+			name:       "moved_file_found_by_identity",
+			path:       filepath.Join(docs, "beach.jpg"),
+			identity:   &Identity{ID: "9:9", Size: 3, ModTime: modTime},
+			commands:   []string{"day2", "day1"},
+			byIdentity: true,
+		},
+		{
+			// Editing a file changes its size and modification time only
+			name:       "modified_file_found_by_identity",
+			path:       filepath.Join(docs, "beach.jpg"),
+			identity:   &Identity{ID: "9:9", Size: 4, ModTime: modTime.Add(time.Hour)},
+			commands:   []string{"day2", "day1"},
+			byIdentity: true,
+		},
+		{
+			name:     "other_file_not_found_by_identity",
+			path:     filepath.Join(docs, "beach.jpg"),
+			identity: &Identity{ID: "9:8", Size: 3, ModTime: modTime},
+		},
+		{
+			// Recorded under the old name of the directory renamed with it
+			name:     "inside_renamed_directory",
+			path:     filepath.Join(docs, "my_dir", "my_file.txt"),
+			identity: &Identity{ID: "7:7"},
+			commands: []string{"day5"},
+		},
+		{
+			name: "failed_rename_ignored",
+			path: filepath.Join(photos, "failed.jpg"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := store.Which(tt.path, tt.identity)
+			assert.Nil(t, err)
+
+			commands := make([]string, 0, len(matches))
+			for _, match := range matches {
+				commands = append(commands, match.Entry.Command)
+			}
+			assert.SliceEqual(t, commands, tt.commands)
+			if len(matches) > 0 {
+				assert.Equal(t, matches[0].ByIdentity, tt.byIdentity)
+			}
+		})
+	}
+}
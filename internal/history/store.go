@@ -2,11 +2,13 @@ package history
 
 // Store defines the interface for history storage operations.
 type Store interface {
-	Save(dirPath string, entry Entry) (string, error)
+	Begin(dirPath string, entry *Entry) error
+
+	Finish(entry *Entry, status Status) error
 
 	Latest(dirPath string) (*Entry, error)
 
-	Delete(dirPath string, historyFile string) error
+	DeleteEntry(entry *Entry) error
 }
 
 type PathIdentifier interface {
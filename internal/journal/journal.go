@@ -0,0 +1,376 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const journalSubDir = "journal"
+
+// errLocked is returned by tryLock when another process holds the journal.
+var errLocked = errors.New("journal is locked by another process")
+
+// errEmpty is returned by open for a journal without a begin record.
+var errEmpty = errors.New("journal is empty")
+
+// Op is a single rename of the batch a journal protects.
+type Op struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Batch describes a rename batch: what was run, where, and which renames it
+// was going to perform, in the order they are applied.
+type Batch struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Path    string    `json:"path"`
+	// WorkDir is the directory relative op and step paths are resolved against.
+	WorkDir string `json:"work_dir"`
+	// HistoryID is the history entry saved as planned for the batch, recovery
+	// records its outcome there. It is empty if history was skipped.
+	HistoryID string `json:"history_id,omitempty"`
+	Ops       []Op   `json:"ops"`
+}
+
+// Step is a single rename recorded while the batch was applied. Besides the
+// batch ops these include renames to temporary names and rollbacks.
+type Step struct {
+	Old  string
+	New  string
+	Done bool
+}
+
+type record struct {
+	Type  string `json:"type"`
+	Batch *Batch `json:"batch,omitempty"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Store keeps write-ahead journals of running rename batches.
+type Store struct {
+	dir string
+}
+
+func NewStore() (*Store, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config dir: %w", err)
+	}
+
+	return &Store{
+		dir: filepath.Join(configDir, "renym", journalSubDir),
+	}, nil
+}
+
+// Journal is an append-only log of a rename batch. It is locked while open,
+// so a running batch is never mistaken for an interrupted one.
+type Journal struct {
+	Batch Batch
+	Steps []Step
+
+	path string
+	file *os.File
+}
+
+// Begin creates and locks a journal for batch. The batch is synced to disk
+// before Begin returns, ahead of any rename.
+func (s *Store) Begin(batch Batch) (*Journal, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal dir: %w", err)
+	}
+
+	if batch.WorkDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working dir: %w", err)
+		}
+		batch.WorkDir = wd
+	}
+
+	name := fmt.Sprintf("%s_%d.jsonl", batch.Time.Format("2006-01-02_150405.000000000"), os.Getpid())
+	path := filepath.Join(s.dir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create journal: %w", err)
+	}
+
+	if err := tryLock(file); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to lock journal: %w", err)
+	}
+
+	j := &Journal{Batch: batch, path: path, file: file}
+
+	if err := j.write(record{Type: "begin", Batch: &batch}); err != nil {
+		j.Remove()
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		j.Remove()
+		return nil, fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	return j, nil
+}
+
+// Interrupted returns the journals left behind by batches that did not
+// finish, oldest first. Journals of batches that are still running or never
+// started are skipped. The returned journals are locked and must be closed or removed.
+func (s *Store) Interrupted() ([]*Journal, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal dir: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var journals []*Journal
+	for _, name := range names {
+		j, err := open(filepath.Join(s.dir, name))
+		if errors.Is(err, errLocked) || errors.Is(err, errEmpty) {
+			continue
+		}
+		if err != nil {
+			for _, opened := range journals {
+				opened.Close()
+			}
+			return nil, err
+		}
+		journals = append(journals, j)
+	}
+
+	return journals, nil
+}
+
+func open(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	// Begin locks a journal before writing to it, so an empty journal is one
+	// that is being created, or whose batch never started. Locking it here
+	// could make that Begin fail.
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	if info.Size() == 0 {
+		file.Close()
+		return nil, errEmpty
+	}
+
+	if err := tryLock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	j := &Journal{path: path, file: file}
+	if err := j.load(); err != nil {
+		j.Close()
+		return nil, fmt.Errorf("failed to read journal %s: %w", filepath.Base(path), err)
+	}
+
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	scanner := bufio.NewScanner(j.file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+
+	var lines [][]byte
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for i, line := range lines {
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// The last line may be torn if the process died mid-write
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch {
+		case i == 0 && rec.Type == "begin" && rec.Batch != nil:
+			j.Batch = *rec.Batch
+		case i == 0:
+			return fmt.Errorf("missing begin record")
+		case rec.Type == "pending":
+			j.Steps = append(j.Steps, Step{Old: rec.Old, New: rec.New})
+		case rec.Type == "done" && len(j.Steps) > 0:
+			j.Steps[len(j.Steps)-1].Done = true
+		}
+	}
+
+	if len(lines) == 0 {
+		return fmt.Errorf("missing begin record")
+	}
+
+	j.resolvePaths()
+	return nil
+}
+
+// resolvePaths makes every op and step path absolute, so recovery does not
+// depend on the directory it is run from.
+func (j *Journal) resolvePaths() {
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(j.Batch.WorkDir, path)
+	}
+
+	j.Batch.Path = abs(j.Batch.Path)
+	for i := range j.Batch.Ops {
+		j.Batch.Ops[i] = Op{Old: abs(j.Batch.Ops[i].Old), New: abs(j.Batch.Ops[i].New)}
+	}
+	for i := range j.Steps {
+		j.Steps[i].Old = abs(j.Steps[i].Old)
+		j.Steps[i].New = abs(j.Steps[i].New)
+	}
+}
+
+// Pending records that oldPath is about to be renamed to newPath.
+func (j *Journal) Pending(oldPath, newPath string) error {
+	return j.write(record{Type: "pending", Old: oldPath, New: newPath})
+}
+
+// Done records that the last pending rename succeeded.
+func (j *Journal) Done() error {
+	return j.write(record{Type: "done"})
+}
+
+// Sync flushes the records written so far to disk. Apply calls it before
+// renames that recovery could not work out from which names exist.
+func (j *Journal) Sync() error {
+	return j.file.Sync()
+}
+
+func (j *Journal) write(rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Close releases the journal but keeps it on disk for a later recovery.
+func (j *Journal) Close() error {
+	unlock(j.file)
+	return j.file.Close()
+}
+
+// Remove closes and deletes the journal once its batch is settled.
+func (j *Journal) Remove() error {
+	j.Close()
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return nil
+}
+
+// Locations returns where the source of every batch op is now. It replays the
+// recorded steps and, for steps that were never confirmed, checks the disk.
+func (j *Journal) Locations() []string {
+	locations := make([]string, len(j.Batch.Ops))
+	byLocation := make(map[string]int, len(j.Batch.Ops))
+	for i, op := range j.Batch.Ops {
+		locations[i] = op.Old
+		byLocation[op.Old] = i
+	}
+
+	for _, step := range j.Steps {
+		if !step.Done && !renamedOnDisk(step) {
+			continue
+		}
+		i, ok := byLocation[step.Old]
+		if !ok {
+			continue
+		}
+		delete(byLocation, step.Old)
+		locations[i] = step.New
+		byLocation[step.New] = i
+	}
+
+	// Fall back to the planned names if the replay does not match the disk
+	for i, op := range j.Batch.Ops {
+		if exists(locations[i]) {
+			continue
+		}
+		if exists(op.New) {
+			locations[i] = op.New
+		} else if exists(op.Old) {
+			locations[i] = op.Old
+		}
+	}
+
+	return locations
+}
+
+// FinishOps returns the renames still needed to complete the batch.
+func (j *Journal) FinishOps() []Op {
+	locations := j.Locations()
+
+	var ops []Op
+	for i, op := range j.Batch.Ops {
+		if locations[i] != op.New {
+			ops = append(ops, Op{Old: locations[i], New: op.New})
+		}
+	}
+	return ops
+}
+
+// RollbackOps returns the renames that restore the batch's original names,
+// in reverse order of the batch.
+func (j *Journal) RollbackOps() []Op {
+	locations := j.Locations()
+
+	var ops []Op
+	for i := len(j.Batch.Ops) - 1; i >= 0; i-- {
+		if op := j.Batch.Ops[i]; locations[i] != op.Old {
+			ops = append(ops, Op{Old: locations[i], New: op.Old})
+		}
+	}
+	return ops
+}
+
+// renamedOnDisk reports whether an unconfirmed step took place.
+func renamedOnDisk(step Step) bool {
+	return !exists(step.Old) && exists(step.New)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,194 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MSmaili/renym/internal/common/testutils"
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return &Store{dir: t.TempDir()}
+}
+
+func testBatch(root string, ops ...Op) Batch {
+	for i := range ops {
+		ops[i] = Op{Old: filepath.Join(root, ops[i].Old), New: filepath.Join(root, ops[i].New)}
+	}
+	return Batch{Time: time.Now(), Command: "renym -m lower", Path: root, WorkDir: root, Ops: ops}
+}
+
+func TestInterrupted(t *testing.T) {
+	store := newTestStore(t)
+	root := t.TempDir()
+
+	j, err := store.Begin(testBatch(root, Op{"a", "b"}, Op{"c", "d"}))
+	assert.Nil(t, err)
+	assert.Nil(t, j.Pending(filepath.Join(root, "a"), filepath.Join(root, "b")))
+	assert.Nil(t, j.Done())
+	assert.Nil(t, j.Pending(filepath.Join(root, "c"), filepath.Join(root, "d")))
+
+	// A batch that is still running is not interrupted
+	journals, err := store.Interrupted()
+	assert.Nil(t, err)
+	assert.Len(t, journals, 0)
+
+	assert.Nil(t, j.Close())
+
+	journals, err = store.Interrupted()
+	assert.Nil(t, err)
+	assert.Len(t, journals, 1)
+	defer journals[0].Close()
+
+	got := journals[0]
+	assert.Equal(t, got.Batch.Command, "renym -m lower")
+	assert.Len(t, got.Batch.Ops, 2)
+	assert.Len(t, got.Steps, 2)
+	assert.True(t, got.Steps[0].Done, "first step should be done")
+	assert.False(t, got.Steps[1].Done, "second step should be pending")
+}
+
+func TestInterruptedIgnoresTornLastLine(t *testing.T) {
+	store := newTestStore(t)
+	root := t.TempDir()
+
+	j, err := store.Begin(testBatch(root, Op{"a", "b"}))
+	assert.Nil(t, err)
+	assert.Nil(t, j.Pending(filepath.Join(root, "a"), filepath.Join(root, "b")))
+	_, err = j.file.WriteString(`{"type":"do`)
+	assert.Nil(t, err)
+	assert.Nil(t, j.Close())
+
+	journals, err := store.Interrupted()
+	assert.Nil(t, err)
+	assert.Len(t, journals, 1)
+	assert.Len(t, journals[0].Steps, 1)
+	journals[0].Close()
+}
+
+func TestInterruptedSkipsEmptyJournal(t *testing.T) {
+	store := newTestStore(t)
+
+	// Begin creates the file before it locks it and writes the begin record
+	path := filepath.Join(store.dir, "2026-01-01_000000.000000000_1.jsonl")
+	assert.Nil(t, os.WriteFile(path, nil, 0644))
+
+	journals, err := store.Interrupted()
+	assert.Nil(t, err)
+	assert.Len(t, journals, 0)
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	defer file.Close()
+	assert.Nil(t, tryLock(file))
+	unlock(file)
+}
+
+func TestRemove(t *testing.T) {
+	store := newTestStore(t)
+
+	j, err := store.Begin(testBatch(t.TempDir(), Op{"a", "b"}))
+	assert.Nil(t, err)
+	assert.Nil(t, j.Remove())
+
+	journals, err := store.Interrupted()
+	assert.Nil(t, err)
+	assert.Len(t, journals, 0)
+}
+
+func TestRecoveryOps(t *testing.T) {
+	tests := []struct {
+		name     string
+		ops      []Op
+		steps    []Step
+		onDisk   []string
+		finish   []Op
+		rollback []Op
+	}{
+		{
+			name:     "stopped_between_renames",
+			ops:      []Op{{"a", "b"}, {"c", "d"}},
+			steps:    []Step{{Old: "a", New: "b", Done: true}},
+			onDisk:   []string{"b", "c"},
+			finish:   []Op{{"c", "d"}},
+			rollback: []Op{{"b", "a"}},
+		},
+		{
+			name:     "unconfirmed_rename_that_happened",
+			ops:      []Op{{"a", "b"}, {"c", "d"}},
+			steps:    []Step{{Old: "a", New: "b", Done: true}, {Old: "c", New: "d"}},
+			onDisk:   []string{"b", "d"},
+			finish:   nil,
+			rollback: []Op{{"d", "c"}, {"b", "a"}},
+		},
+		{
+			name:     "unconfirmed_rename_that_did_not_happen",
+			ops:      []Op{{"a", "b"}},
+			steps:    []Step{{Old: "a", New: "b"}},
+			onDisk:   []string{"a"},
+			finish:   []Op{{"a", "b"}},
+			rollback: nil,
+		},
+		{
+			name: "swap_stopped_while_staged",
+			ops:  []Op{{"a", "b"}, {"b", "a"}},
+			steps: []Step{
+				{Old: "b", New: ".tmp-b", Done: true},
+				{Old: "a", New: "b", Done: true},
+			},
+			onDisk:   []string{".tmp-b", "b"},
+			finish:   []Op{{".tmp-b", "a"}},
+			rollback: []Op{{".tmp-b", "b"}, {"b", "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			testutils.CreateFiles(t, root, tt.onDisk)
+
+			abs := func(ops []Op) []Op {
+				var result []Op
+				for _, op := range ops {
+					result = append(result, Op{Old: filepath.Join(root, op.Old), New: filepath.Join(root, op.New)})
+				}
+				return result
+			}
+
+			j := &Journal{Batch: testBatch(root, tt.ops...)}
+			for _, step := range tt.steps {
+				j.Steps = append(j.Steps, Step{Old: filepath.Join(root, step.Old), New: filepath.Join(root, step.New), Done: step.Done})
+			}
+
+			assert.SliceEqual(t, j.FinishOps(), abs(tt.finish))
+			assert.SliceEqual(t, j.RollbackOps(), abs(tt.rollback))
+		})
+	}
+}
+
+func TestLoadResolvesRelativePaths(t *testing.T) {
+	store := newTestStore(t)
+	root := t.TempDir()
+
+	batch := Batch{Time: time.Now(), WorkDir: root, Ops: []Op{{Old: "a", New: "b"}}}
+	j, err := store.Begin(batch)
+	assert.Nil(t, err)
+	assert.Nil(t, j.Pending("a", "b"))
+	assert.Nil(t, j.Close())
+
+	journals, err := store.Interrupted()
+	assert.Nil(t, err)
+	assert.Len(t, journals, 1)
+	defer journals[0].Close()
+
+	assert.Equal(t, journals[0].Batch.Ops[0].Old, filepath.Join(root, "a"))
+	assert.Equal(t, journals[0].Steps[0].New, filepath.Join(root, "b"))
+
+	if _, err := os.Stat(journals[0].path); err != nil {
+		t.Errorf("expected journal to stay on disk: %v", err)
+	}
+}
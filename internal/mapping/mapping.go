@@ -0,0 +1,140 @@
+package mapping
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Pair is a single old → new rename read from a mapping file.
+type Pair struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Load reads rename pairs from a .csv, .tsv or .json file.
+//
+// CSV and TSV files hold two columns, old and new, with an optional
+// "old,new" header row. JSON files hold either an array of
+// {"old": ..., "new": ...} objects or a single {"old": "new"} object.
+func Load(path string) ([]Pair, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mapping file: %w", err)
+	}
+	defer f.Close()
+
+	var pairs []Pair
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		pairs, err = readDelimited(f, ',')
+	case ".tsv":
+		pairs, err = readDelimited(f, '\t')
+	case ".json":
+		pairs, err = readJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported mapping file type '%s', use .csv, .tsv or .json", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// Resolve makes pair paths usable for renaming: relative paths are taken
+// relative to baseDir, and a new value without a directory stays in the
+// directory of the old path.
+func Resolve(pairs []Pair, baseDir string) []Pair {
+	resolved := make([]Pair, len(pairs))
+	for i, p := range pairs {
+		oldPath := p.Old
+		if !filepath.IsAbs(oldPath) {
+			oldPath = filepath.Join(baseDir, oldPath)
+		}
+
+		newPath := p.New
+		switch {
+		case filepath.IsAbs(newPath):
+		case filepath.Base(newPath) == newPath:
+			newPath = filepath.Join(filepath.Dir(oldPath), newPath)
+		default:
+			newPath = filepath.Join(baseDir, newPath)
+		}
+
+		resolved[i] = Pair{Old: filepath.Clean(oldPath), New: filepath.Clean(newPath)}
+	}
+	return resolved
+}
+
+func readDelimited(r io.Reader, delimiter rune) ([]Pair, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = 2
+	if delimiter == '\t' {
+		reader.LazyQuotes = true
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) > 0 && isHeader(records[0]) {
+		records = records[1:]
+	}
+
+	pairs := make([]Pair, 0, len(records))
+	for i, record := range records {
+		if record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("row %d: old and new must not be empty", i+1)
+		}
+		pairs = append(pairs, Pair{Old: record[0], New: record[1]})
+	}
+	return pairs, nil
+}
+
+func isHeader(record []string) bool {
+	return strings.EqualFold(strings.TrimSpace(record[0]), "old") &&
+		strings.EqualFold(strings.TrimSpace(record[1]), "new")
+}
+
+func readJSON(r io.Reader) ([]Pair, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []Pair
+	if err := json.Unmarshal(data, &pairs); err == nil {
+		for i, p := range pairs {
+			if p.Old == "" || p.New == "" {
+				return nil, fmt.Errorf("entry %d: old and new must not be empty", i+1)
+			}
+		}
+		return pairs, nil
+	}
+
+	var object map[string]string
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("expected an array of {\"old\", \"new\"} objects or an {\"old\": \"new\"} object")
+	}
+
+	pairs = make([]Pair, 0, len(object))
+	for oldName, newName := range object {
+		if oldName == "" || newName == "" {
+			return nil, fmt.Errorf("old and new must not be empty")
+		}
+		pairs = append(pairs, Pair{Old: oldName, New: newName})
+	}
+	// Map iteration order is random, keep the result deterministic
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Old < pairs[j].Old
+	})
+	return pairs, nil
+}
@@ -0,0 +1,135 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected []Pair
+	}{
+		{
+			name:     "csv",
+			file:     "map.csv",
+			content:  "a.txt,b.txt\nc.txt,d.txt\n",
+			expected: []Pair{{"a.txt", "b.txt"}, {"c.txt", "d.txt"}},
+		},
+		{
+			name:     "csv_with_header_and_quotes",
+			file:     "map.csv",
+			content:  "old,new\n\"Q1, draft.txt\",q1.txt\n",
+			expected: []Pair{{"Q1, draft.txt", "q1.txt"}},
+		},
+		{
+			name:     "tsv",
+			file:     "map.tsv",
+			content:  "Old\tNew\nmy file.txt\tmy_file.txt\n",
+			expected: []Pair{{"my file.txt", "my_file.txt"}},
+		},
+		{
+			name:     "leading_spaces_kept",
+			file:     "map.csv",
+			content:  " report.txt, final.txt\n",
+			expected: []Pair{{" report.txt", " final.txt"}},
+		},
+		{
+			name:     "json_array",
+			file:     "map.json",
+			content:  `[{"old": "a.txt", "new": "b.txt"}]`,
+			expected: []Pair{{"a.txt", "b.txt"}},
+		},
+		{
+			name:     "json_object",
+			file:     "map.json",
+			content:  `{"c.txt": "d.txt", "a.txt": "b.txt"}`,
+			expected: []Pair{{"a.txt", "b.txt"}, {"c.txt", "d.txt"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := Load(writeFile(t, tt.file, tt.content))
+			assert.Nil(t, err)
+			assert.SliceEqual(t, pairs, tt.expected)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"unsupported_extension", "map.txt", "a.txt,b.txt"},
+		{"wrong_column_count", "map.csv", "a.txt,b.txt,c.txt\n"},
+		{"empty_value", "map.csv", "a.txt,\n"},
+		{"json_missing_new", "map.json", `[{"old": "a.txt"}]`},
+		{"json_invalid", "map.json", `"a.txt"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, tt.file, tt.content))
+			assert.NotNil(t, err)
+		})
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.NotNil(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	base := filepath.Join("data", "photos")
+	abs := filepath.Join(string(filepath.Separator), "abs")
+
+	tests := []struct {
+		name     string
+		pair     Pair
+		expected Pair
+	}{
+		{
+			name:     "bare_names_stay_in_base",
+			pair:     Pair{"a.jpg", "b.jpg"},
+			expected: Pair{filepath.Join(base, "a.jpg"), filepath.Join(base, "b.jpg")},
+		},
+		{
+			name:     "bare_new_name_stays_in_old_dir",
+			pair:     Pair{filepath.Join("2024", "a.jpg"), "b.jpg"},
+			expected: Pair{filepath.Join(base, "2024", "a.jpg"), filepath.Join(base, "2024", "b.jpg")},
+		},
+		{
+			name:     "relative_new_path_uses_base",
+			pair:     Pair{filepath.Join("2024", "a.jpg"), filepath.Join("2024", "b.jpg")},
+			expected: Pair{filepath.Join(base, "2024", "a.jpg"), filepath.Join(base, "2024", "b.jpg")},
+		},
+		{
+			name:     "absolute_paths_untouched",
+			pair:     Pair{filepath.Join(abs, "a.jpg"), "b.jpg"},
+			expected: Pair{filepath.Join(abs, "a.jpg"), filepath.Join(abs, "b.jpg")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Resolve([]Pair{tt.pair}, base)
+			assert.Equal(t, result[0], tt.expected)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package walker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// filter selects the walked paths that become candidates. Every configured
+// filter must match.
+type filter struct {
+	include    []string
+	extensions []string
+	match      *regexp.Regexp
+}
+
+func newFilter(cfg Config) (*filter, error) {
+	include, err := slashPatterns(cfg.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &filter{include: include}
+	for _, value := range cfg.Extensions {
+		ext := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "."))
+		if ext == "" {
+			return nil, fmt.Errorf("invalid extension '%s'", value)
+		}
+		f.extensions = append(f.extensions, "."+ext)
+	}
+
+	if cfg.Match != "" {
+		re, err := regexp.Compile(cfg.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match regex '%s': %w", cfg.Match, err)
+		}
+		f.match = re
+	}
+
+	return f, nil
+}
+
+// selects reports whether the path with the given name and slash separated
+// path rel, relative to the walked directory, is a candidate. Extensions
+// only apply to files.
+func (f *filter) selects(name, rel string, isDir bool) bool {
+	if len(f.include) > 0 && !f.included(name, rel) {
+		return false
+	}
+	if len(f.extensions) > 0 && !isDir && !f.hasExtension(name) {
+		return false
+	}
+	if f.match != nil && !f.match.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+func (f *filter) included(name, rel string) bool {
+	for _, pattern := range f.include {
+		if MatchPattern(pattern, name, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExtension compares case-insensitively and allows multi-part extensions
+// such as tar.gz.
+func (f *filter) hasExtension(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range f.extensions {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return true
+		}
+	}
+	return false
+}
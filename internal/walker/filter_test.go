@@ -0,0 +1,58 @@
+package walker
+
+import (
+	"path"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestFilterSelects(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"no filters", Config{}, "a.txt", false, true},
+		{"extension", Config{Extensions: []string{"jpg", "png"}}, "x/a.PNG", false, true},
+		{"extension with dot", Config{Extensions: []string{".jpg"}}, "a.jpg", false, true},
+		{"other extension", Config{Extensions: []string{"jpg"}}, "a.jpeg", false, false},
+		{"multi-part extension", Config{Extensions: []string{"tar.gz"}}, "backup.tar.gz", false, true},
+		{"extension only", Config{Extensions: []string{"jpg"}}, ".jpg", false, false},
+		{"extension skips directories", Config{Extensions: []string{"jpg"}}, "photos", true, true},
+		{"match", Config{Match: `^IMG_\d+`}, "x/IMG_0001.jpg", false, true},
+		{"match the name only", Config{Match: `^x/`}, "x/IMG_0001.jpg", false, false},
+		{"match directories", Config{Match: `^IMG`}, "photos", true, false},
+		{"include", Config{Include: []string{"docs/**/*.md"}}, "docs/a/b.md", false, true},
+		{"every filter must match", Config{Include: []string{"*.jpg"}, Match: `^IMG`}, "DSC_1.jpg", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFilter(tt.cfg)
+			assert.Nil(t, err)
+
+			assert.Equal(t, f.selects(path.Base(tt.rel), tt.rel, tt.isDir), tt.want)
+		})
+	}
+}
+
+func TestNewFilterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"include", Config{Include: []string{"[a-"}}},
+		{"extension", Config{Extensions: []string{"."}}},
+		{"match", Config{Match: "("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFilter(tt.cfg)
+			assert.NotNil(t, err)
+		})
+	}
+}
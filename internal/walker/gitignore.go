@@ -0,0 +1,132 @@
+package walker
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const gitignoreFile = ".gitignore"
+
+// loadGitignore returns the git exclude rules that apply above and at root:
+// the global excludes file, .git/info/exclude and the .gitignore files from
+// the repository root down to root. Rules of .gitignore files below root are
+// added while walking. Outside a repository only .gitignore files from root
+// down apply.
+// root must be absolute.
+func loadGitignore(root string) (*ignoreRules, error) {
+	rules := &ignoreRules{}
+
+	repo, ok := findRepoRoot(root)
+	if !ok {
+		return rules, rules.addFile(filepath.Join(root, gitignoreFile), root)
+	}
+
+	if global := globalExcludesFile(); global != "" {
+		if err := rules.addFile(global, repo); err != nil {
+			return nil, err
+		}
+	}
+	if err := rules.addFile(filepath.Join(repo, ".git", "info", "exclude"), repo); err != nil {
+		return nil, err
+	}
+
+	// .gitignore files from the repository root down to root
+	var dirs []string
+	for dir := root; ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == repo {
+			break
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := rules.addFile(filepath.Join(dirs[i], gitignoreFile), dirs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// findRepoRoot returns the nearest directory at or above dir that contains
+// .git, a directory or, for worktrees and submodules, a file. dir must be
+// absolute.
+func findRepoRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// globalExcludesFile returns git's core.excludesFile, or its default
+// location when it is not configured.
+func globalExcludesFile() string {
+	home, _ := os.UserHomeDir()
+
+	for _, config := range gitConfigFiles(home) {
+		if path := readExcludesFile(config); path != "" {
+			if rest, ok := strings.CutPrefix(path, "~/"); ok && home != "" {
+				return filepath.Join(home, rest)
+			}
+			return path
+		}
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if home != "" {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// gitConfigFiles returns the global git config files, highest precedence first.
+func gitConfigFiles(home string) []string {
+	var files []string
+	if home != "" {
+		files = append(files, filepath.Join(home, ".gitconfig"))
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		files = append(files, filepath.Join(xdg, "git", "config"))
+	} else if home != "" {
+		files = append(files, filepath.Join(home, ".config", "git", "config"))
+	}
+	return files
+}
+
+// readExcludesFile returns the excludesFile setting of the [core] section of
+// a git config file. Includes and conditional sections are not followed.
+func readExcludesFile(config string) string {
+	file, err := os.Open(config)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inCore := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inCore = strings.EqualFold(strings.Trim(line, "[] "), "core")
+			continue
+		}
+		if !inCore {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "excludesfile") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+package walker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestReadExcludesFile(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	content := "[user]\n\texcludesfile = /wrong\n[core]\n\teditor = vim\n\texcludesFile = \"~/.gitignore_global\"\n"
+	assert.Nil(t, os.WriteFile(config, []byte(content), 0644))
+
+	assert.Equal(t, readExcludesFile(config), "~/.gitignore_global")
+	assert.Equal(t, readExcludesFile(filepath.Join(t.TempDir(), "missing")), "")
+}
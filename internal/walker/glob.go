@@ -0,0 +1,76 @@
+package walker
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchPattern matches a --ignore, --include, --only or --exclude pattern. A
+// pattern without a slash matches the name at any depth, any other matches
+// rel, the slash separated path relative to the walked or undone directory.
+// A leading slash is optional.
+func MatchPattern(pattern, name, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		return matchGlob(pattern, name)
+	}
+	return matchGlob(strings.TrimPrefix(pattern, "/"), rel)
+}
+
+// ValidGlob reports a malformed segment of pattern, see MatchPattern.
+func ValidGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// slashPatterns returns patterns with slash separators, as MatchPattern
+// expects, and reports the first malformed one.
+func slashPatterns(patterns []string) ([]string, error) {
+	normalized := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		normalized[i] = filepath.ToSlash(pattern)
+		if err := ValidGlob(normalized[i]); err != nil {
+			return nil, err
+		}
+	}
+	return normalized, nil
+}
+
+// matchGlob reports whether name matches pattern, both separated by slashes.
+// A "**" segment matches any number of segments, including none, except as
+// the last segment where it matches at least one. Other segments are matched
+// with path.Match.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return len(name) > 0
+			}
+			for i := range len(name) + 1 {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if matched, err := path.Match(pattern[0], name[0]); err != nil || !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
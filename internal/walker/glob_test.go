@@ -0,0 +1,79 @@
+package walker
+
+import (
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.txt", "a.txt", true},
+		{"*.txt", "dir/a.txt", false},
+		{"dir/*.txt", "dir/a.txt", true},
+		{"dir/*.txt", "dir/sub/a.txt", false},
+		{"**/a.txt", "a.txt", true},
+		{"**/a.txt", "x/y/a.txt", true},
+		{"dir/**/a.txt", "dir/a.txt", true},
+		{"dir/**/a.txt", "dir/x/y/a.txt", true},
+		{"dir/**/a.txt", "other/a.txt", false},
+		{"dir/**", "dir/a/b", true},
+		{"dir/**", "dir", false},
+		{"**", "a/b", true},
+		{"a?c", "abc", true},
+		{"[ab]*", "bcd", true},
+		{"[ab]*", "cd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.name, func(t *testing.T) {
+			assert.Equal(t, matchGlob(tt.pattern, tt.name), tt.want)
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"*.psd", "a.psd", true},
+		{"*.psd", "assets/x/a.psd", true},
+		{"raw", "assets/raw", true},
+		{"docs/*.md", "docs/a.md", true},
+		{"docs/*.md", "docs/sub/a.md", false},
+		{"docs/*.md", "other/docs/a.md", false},
+		{"/docs/*.md", "docs/a.md", true},
+		{"assets/**/raw/*.psd", "assets/raw/a.psd", true},
+		{"assets/**/raw/*.psd", "assets/x/y/raw/a.psd", true},
+		{"assets/**/raw/*.psd", "assets/x/a.psd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.rel, func(t *testing.T) {
+			name := path.Base(tt.rel)
+			assert.Equal(t, MatchPattern(tt.pattern, name, tt.rel), tt.want)
+		})
+	}
+}
+
+func TestValidGlob(t *testing.T) {
+	assert.Nil(t, ValidGlob("assets/**/raw/*.psd"))
+	assert.NotNil(t, ValidGlob("docs/[a-"))
+}
+
+func TestSlashPatterns(t *testing.T) {
+	patterns, err := slashPatterns([]string{filepath.Join("sub", "*.tmp"), "*.log"})
+	assert.Nil(t, err)
+	assert.SliceEqual(t, patterns, []string{"sub/*.tmp", "*.log"})
+
+	_, err = slashPatterns([]string{filepath.Join("sub", "[a-")})
+	assert.NotNil(t, err)
+}
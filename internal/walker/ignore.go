@@ -0,0 +1,180 @@
+package walker
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single pattern of a gitignore style file.
+type ignoreRule struct {
+	// base is the directory the pattern is relative to.
+	base    string
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules matches paths against gitignore style rules. Like git, the
+// last matching rule wins, so rules must be added from lowest to highest
+// precedence.
+type ignoreRules struct {
+	rules []ignoreRule
+}
+
+// parseIgnoreLine parses a line of a gitignore style file relative to base.
+// It returns false for blank lines and comments.
+func parseIgnoreLine(base, line string) (ignoreRule, bool) {
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// A pattern with a slash is anchored to base, any other matches at any depth
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else if line != "" {
+		line = "**/" + line
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = line
+	return rule, true
+}
+
+// trimTrailingSpaces removes trailing spaces unless they are escaped.
+func trimTrailingSpaces(line string) string {
+	line = strings.TrimRight(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// addFile adds the rules of a gitignore style file relative to base. A
+// missing file adds nothing.
+func (r *ignoreRules) addFile(path, base string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(base, scanner.Text()); ok {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	return scanner.Err()
+}
+
+// ignored reports whether path is ignored by the rules.
+func (r *ignoreRules) ignored(path string, isDir bool) bool {
+	ignored, _ := r.match(path, isDir)
+	return ignored
+}
+
+// match reports whether the last rule matching path ignores it, and whether
+// any rule matched at all.
+func (r *ignoreRules) match(path string, isDir bool) (ignored, matched bool) {
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if matchGlob(rule.pattern, filepath.ToSlash(rel)) {
+			ignored, matched = !rule.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// ignoreLevel holds the git and .renymignore rules of one directory.
+type ignoreLevel struct {
+	dir   string
+	git   ignoreRules
+	renym ignoreRules
+}
+
+// ignoreStack holds the ignore rules in effect during a walk: the rules that
+// apply at the walked directory and those of every directory between it and
+// the current path. .renymignore rules take precedence over git rules, and
+// within each, rules of a deeper directory take precedence.
+type ignoreStack struct {
+	root   ignoreLevel
+	levels []ignoreLevel
+}
+
+// enter drops the rules of the directories the walk has left. path is the
+// absolute path about to be visited, in walk order.
+func (s *ignoreStack) enter(path string) {
+	for len(s.levels) > 0 {
+		dir := s.levels[len(s.levels)-1].dir
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return
+		}
+		s.levels = s.levels[:len(s.levels)-1]
+	}
+}
+
+// addDir adds the ignore files of the walked directory dir, whose absolute
+// path is absDir. They apply until the walk leaves absDir.
+func (s *ignoreStack) addDir(dir, absDir string, gitIgnore bool) error {
+	level := ignoreLevel{dir: absDir}
+	if gitIgnore {
+		if err := level.git.addFile(filepath.Join(dir, gitignoreFile), absDir); err != nil {
+			return err
+		}
+	}
+	if err := level.renym.addFile(filepath.Join(dir, renymignoreFile), absDir); err != nil {
+		return err
+	}
+
+	if len(level.git.rules) > 0 || len(level.renym.rules) > 0 {
+		s.levels = append(s.levels, level)
+	}
+	return nil
+}
+
+// ignored reports whether path, below every directory entered, is ignored.
+func (s *ignoreStack) ignored(path string, isDir bool) bool {
+	ignored := false
+	apply := func(rules *ignoreRules) {
+		if ruleIgnored, ok := rules.match(path, isDir); ok {
+			ignored = ruleIgnored
+		}
+	}
+
+	apply(&s.root.git)
+	for i := range s.levels {
+		apply(&s.levels[i].git)
+	}
+	apply(&s.root.renym)
+	for i := range s.levels {
+		apply(&s.levels[i].renym)
+	}
+	return ignored
+}
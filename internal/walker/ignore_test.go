@@ -0,0 +1,83 @@
+package walker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/MSmaili/renym/internal/common/testutils/assert"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{line: ""},
+		{line: "   "},
+		{line: "# comment"},
+		{line: "*.log", want: ignoreRule{pattern: "**/*.log"}, ok: true},
+		{line: "*.log   ", want: ignoreRule{pattern: "**/*.log"}, ok: true},
+		{line: `trailing\ `, want: ignoreRule{pattern: `**/trailing\ `}, ok: true},
+		{line: "!keep.log", want: ignoreRule{pattern: "**/keep.log", negate: true}, ok: true},
+		{line: `\!bang`, want: ignoreRule{pattern: "**/!bang"}, ok: true},
+		{line: `\#hash`, want: ignoreRule{pattern: "**/#hash"}, ok: true},
+		{line: "build/", want: ignoreRule{pattern: "**/build", dirOnly: true}, ok: true},
+		{line: "/dist", want: ignoreRule{pattern: "dist"}, ok: true},
+		{line: "doc/*.txt", want: ignoreRule{pattern: "doc/*.txt"}, ok: true},
+		{line: "**/out/", want: ignoreRule{pattern: "**/out", dirOnly: true}, ok: true},
+		{line: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			rule, ok := parseIgnoreLine("base", tt.line)
+			assert.Equal(t, ok, tt.ok)
+			if tt.ok {
+				tt.want.base = "base"
+				assert.Equal(t, rule, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreRulesIgnored(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	sub := filepath.Join(root, "sub")
+
+	rules := &ignoreRules{}
+	for _, line := range []string{"*.log", "!important.log", "/generated", "build/", "docs/**/*.tmp"} {
+		rule, _ := parseIgnoreLine(root, line)
+		rules.rules = append(rules.rules, rule)
+	}
+	// A deeper file re-ignores what the root file re-included
+	rule, _ := parseIgnoreLine(sub, "important.log")
+	rules.rules = append(rules.rules, rule)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "a.log", want: true},
+		{path: "x/y/a.log", want: true},
+		{path: "important.log", want: false},
+		{path: "sub/important.log", want: true},
+		{path: "generated", isDir: true, want: true},
+		{path: "x/generated", isDir: true, want: false},
+		{path: "build", isDir: true, want: true},
+		{path: "x/build", isDir: true, want: true},
+		{path: "build", isDir: false, want: false},
+		{path: "docs/a.tmp", want: true},
+		{path: "docs/x/y/a.tmp", want: true},
+		{path: "a.tmp", want: false},
+		{path: "main.go", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(root, filepath.FromSlash(tt.path))
+			assert.Equal(t, rules.ignored(path, tt.isDir), tt.want)
+		})
+	}
+}
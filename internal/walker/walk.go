@@ -4,17 +4,43 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type Config struct {
-	Path            string
-	Recursive       bool
-	Files           bool
-	Directories     bool
-	Ignore          []string
+	Path string
+	// MaxDepth limits how deep the walk goes, 1 only sees the entries of
+	// Path itself. 0 means no limit.
+	MaxDepth int
+	// MinDepth skips entries less deep, they are still walked. 0 or 1 means
+	// no limit.
+	MinDepth    int
+	Files       bool
+	Directories bool
+	// Ignore skips paths matching any of these patterns. A pattern without a
+	// slash matches the name, any other the path relative to Path, where
+	// "**" matches any number of directories.
+	Ignore []string
+	// Include keeps only files and directories matching one of these
+	// patterns, matched like Ignore. Directories are walked regardless.
+	Include []string
+	// Extensions keeps only files with one of these extensions, such as jpg
+	// or tar.gz, compared case-insensitively.
+	Extensions []string
+	// Match keeps only files and directories whose name matches this
+	// regular expression.
+	Match           string
 	NoDefaultIgnore bool
+	// GitIgnore skips paths ignored by git: .gitignore files, the
+	// repository's .git/info/exclude and the global excludes file.
+	GitIgnore bool
 }
 
+// renymignoreFile lists paths to skip in gitignore syntax. It applies to its
+// directory and everything below, always, on top of the ignore patterns.
+const renymignoreFile = ".renymignore"
+
 func isFile(path string) (bool, error) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -35,11 +61,37 @@ func Walk(cfg Config) ([]string, error) {
 		return []string{}, nil
 	}
 
+	ignorePatterns, err := slashPatterns(cfg.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := newFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	paths := make([]string, 0, 100)
 
-	ignorePatterns := cfg.Ignore
 	if !cfg.NoDefaultIgnore {
-		ignorePatterns = append(DefaultIgnorePatterns, cfg.Ignore...)
+		ignorePatterns = append(slices.Clone(DefaultIgnorePatterns), ignorePatterns...)
+	}
+
+	// Ignore rules use absolute paths, walked paths keep the form of cfg.Path
+	absRoot, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	ignores := &ignoreStack{}
+	if cfg.GitIgnore {
+		git, err := loadGitignore(absRoot)
+		if err != nil {
+			return nil, err
+		}
+		ignores.root.git = *git
+	}
+	if err := ignores.root.renym.addFile(filepath.Join(cfg.Path, renymignoreFile), absRoot); err != nil {
+		return nil, err
 	}
 
 	err = filepath.WalkDir(cfg.Path, func(path string, d fs.DirEntry, err error) error {
@@ -51,28 +103,51 @@ func Walk(cfg Config) ([]string, error) {
 			return nil
 		}
 
+		skip := func() error {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(cfg.Path, path)
+		if err != nil {
+			return err
+		}
+
 		name := d.Name()
+		relSlash := filepath.ToSlash(rel)
 		for _, pattern := range ignorePatterns {
-			matched, err := filepath.Match(pattern, name)
-			if err != nil {
-				continue
+			if MatchPattern(pattern, name, relSlash) {
+				return skip()
 			}
-			if matched {
-				if d.IsDir() {
-					return fs.SkipDir
-				}
-				return nil
+		}
+
+		absPath := filepath.Join(absRoot, rel)
+		ignores.enter(absPath)
+
+		if (cfg.GitIgnore && name == ".git") || ignores.ignored(absPath, d.IsDir()) {
+			return skip()
+		}
+
+		depth := strings.Count(relSlash, "/") + 1
+		descend := cfg.MaxDepth == 0 || depth < cfg.MaxDepth
+		if d.IsDir() && descend {
+			if err := ignores.addDir(path, absPath, cfg.GitIgnore); err != nil {
+				return err
 			}
 		}
 
+		included := depth >= cfg.MinDepth && filter.selects(name, relSlash, d.IsDir())
+
 		if d.IsDir() {
-			if cfg.Directories {
+			if cfg.Directories && included {
 				paths = append(paths, path)
 			}
-			if !cfg.Recursive {
+			if !descend {
 				return fs.SkipDir
 			}
-		} else if cfg.Files {
+		} else if cfg.Files && included {
 			paths = append(paths, path)
 		}
 
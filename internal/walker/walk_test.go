@@ -21,8 +21,8 @@ func TestWalk(t *testing.T) {
 			name:  "non-recursive only sees top-level files",
 			files: []string{"file1.txt", "sub/file3.txt"},
 			cfg: Config{
-				Recursive: false,
-				Files:     true,
+				MaxDepth: 1,
+				Files:    true,
 			},
 			want: []string{"file1.txt"},
 		},
@@ -33,8 +33,7 @@ func TestWalk(t *testing.T) {
 				"file1.txt",
 			},
 			cfg: Config{
-				Recursive: true,
-				Files:     true,
+				Files: true,
 			},
 			want: []string{"sub/file2.txt", "file1.txt"},
 		},
@@ -80,7 +79,6 @@ func TestWalk(t *testing.T) {
 				"dir2/file.txt",
 			},
 			cfg: Config{
-				Recursive:   true,
 				Directories: true,
 				Files:       false,
 			},
@@ -93,7 +91,6 @@ func TestWalk(t *testing.T) {
 				"dir/file.txt",
 			},
 			cfg: Config{
-				Recursive:   true,
 				Directories: true,
 				Files:       true,
 			},
@@ -106,7 +103,6 @@ func TestWalk(t *testing.T) {
 				"dir/test/hello.txt",
 			},
 			cfg: Config{
-				Recursive:   true,
 				Directories: true,
 				Files:       false,
 			},
@@ -119,7 +115,7 @@ func TestWalk(t *testing.T) {
 				"dir/test/hello.txt",
 			},
 			cfg: Config{
-				Recursive:   false,
+				MaxDepth:    1,
 				Directories: true,
 				Files:       false,
 			},
@@ -151,6 +147,316 @@ func TestWalk(t *testing.T) {
 	}
 }
 
+func TestWalkGitIgnore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	writeFile(t, filepath.Join(home, ".config", "git", "ignore"), "*.swp\n")
+
+	repo := t.TempDir()
+	createFiles(t, repo, []string{
+		".git/info/",
+		"main.go",
+		"main.go.swp",
+		"notes.local",
+		"app.log",
+		"important.log",
+		"gen/code.go",
+		"src/util.go",
+		"src/cache/data.bin",
+		"src/keep.tmp",
+		"src/drop.tmp",
+	})
+	writeFile(t, filepath.Join(repo, ".git", "info", "exclude"), "*.local\n")
+	writeFile(t, filepath.Join(repo, ".gitignore"), "*.log\n!important.log\n/gen/\n*.tmp\n")
+	writeFile(t, filepath.Join(repo, "src", ".gitignore"), "cache/\n!keep.tmp\n")
+
+	tests := []struct {
+		name string
+		path string
+		cfg  Config
+		want []string
+	}{
+		{
+			name: "recursive",
+			path: repo,
+			cfg:  Config{Files: true, GitIgnore: true},
+			want: []string{".gitignore", "important.log", "main.go", "src/.gitignore", "src/keep.tmp", "src/util.go"},
+		},
+		{
+			name: "directories",
+			path: repo,
+			cfg:  Config{Directories: true, GitIgnore: true, NoDefaultIgnore: true},
+			want: []string{"src"},
+		},
+		{
+			// The rules of the parent .gitignore files apply below the repository root too
+			name: "subdirectory",
+			path: filepath.Join(repo, "src"),
+			cfg:  Config{MaxDepth: 1, Files: true, GitIgnore: true},
+			want: []string{".gitignore", "keep.tmp", "util.go"},
+		},
+		{
+			name: "disabled",
+			path: filepath.Join(repo, "src"),
+			cfg:  Config{MaxDepth: 1, Files: true},
+			want: []string{".gitignore", "drop.tmp", "keep.tmp", "util.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Path = tt.path
+
+			got, err := Walk(cfg)
+			assert.Nil(t, err)
+
+			for i := range got {
+				rel, _ := filepath.Rel(tt.path, got[i])
+				got[i] = filepath.ToSlash(rel)
+			}
+			sort.Strings(got)
+
+			assert.SliceEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestWalkRenymIgnore(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, []string{
+		"a.txt",
+		"b.bak",
+		"keep.bak",
+		"drafts/c.txt",
+		"photos/d.jpg",
+		"photos/raw/e.cr2",
+		"photos/f.bak",
+		"repo/app.log",
+		"repo/notes.log",
+	})
+	writeFile(t, filepath.Join(root, renymignoreFile), "*.bak\n!keep.bak\ndrafts/\n")
+	writeFile(t, filepath.Join(root, "photos", renymignoreFile), "raw/\n!f.bak\n")
+	writeFile(t, filepath.Join(root, "repo", ".gitignore"), "*.log\n")
+	writeFile(t, filepath.Join(root, "repo", renymignoreFile), "!notes.log\n")
+
+	tests := []struct {
+		name string
+		path string
+		cfg  Config
+		want []string
+	}{
+		{
+			name: "recursive",
+			path: root,
+			cfg:  Config{Files: true},
+			want: []string{".renymignore", "a.txt", "keep.bak", "photos/.renymignore", "photos/d.jpg", "photos/f.bak", "repo/.gitignore", "repo/.renymignore", "repo/app.log", "repo/notes.log"},
+		},
+		{
+			// Without recursion only the root file applies
+			name: "not recursive",
+			path: root,
+			cfg:  Config{MaxDepth: 1, Files: true, Directories: true},
+			want: []string{".renymignore", "a.txt", "keep.bak", "photos", "repo"},
+		},
+		{
+			name: "subdirectory",
+			path: filepath.Join(root, "photos"),
+			cfg:  Config{Files: true},
+			want: []string{".renymignore", "d.jpg", "f.bak"},
+		},
+		{
+			// .renymignore rules take precedence over .gitignore rules
+			name: "with gitignore",
+			path: filepath.Join(root, "repo"),
+			cfg:  Config{MaxDepth: 1, Files: true, GitIgnore: true},
+			want: []string{".gitignore", ".renymignore", "notes.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Path = tt.path
+
+			got, err := Walk(cfg)
+			assert.Nil(t, err)
+
+			for i := range got {
+				rel, _ := filepath.Rel(tt.path, got[i])
+				got[i] = filepath.ToSlash(rel)
+			}
+			sort.Strings(got)
+
+			assert.SliceEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestWalkIgnoreScopes(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, []string{
+		"a/x.tmp",
+		"a/keep.tmp",
+		"a/notes.txt",
+		"b/notes.txt",
+	})
+	writeFile(t, filepath.Join(root, renymignoreFile), "*.tmp\n")
+	// A child .gitignore cannot re-include what a parent .renymignore excludes
+	writeFile(t, filepath.Join(root, "a", ".gitignore"), "!keep.tmp\n")
+	// Rules of a directory do not leak into its siblings
+	writeFile(t, filepath.Join(root, "a", renymignoreFile), "notes.txt\n")
+
+	got, err := Walk(Config{Path: root, Files: true, GitIgnore: true})
+	assert.Nil(t, err)
+
+	for i := range got {
+		rel, _ := filepath.Rel(root, got[i])
+		got[i] = filepath.ToSlash(rel)
+	}
+	sort.Strings(got)
+
+	assert.SliceEqual(t, got, []string{".renymignore", "a/.gitignore", "a/.renymignore", "b/notes.txt"})
+}
+
+func TestWalkPatterns(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, []string{
+		"README.md",
+		"docs/guide.md",
+		"docs/notes.txt",
+		"docs/api/index.md",
+		"assets/logo.psd",
+		"assets/raw/a.psd",
+		"assets/icons/raw/b.psd",
+		"assets/icons/raw/b.png",
+	})
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{
+			name: "ignore by path",
+			cfg:  Config{Files: true, Ignore: []string{"assets/**/raw/*.psd", "docs/*.md"}},
+			want: []string{"README.md", "assets/icons/raw/b.png", "assets/logo.psd", "docs/api/index.md", "docs/notes.txt"},
+		},
+		{
+			name: "ignore by name",
+			cfg:  Config{Files: true, Ignore: []string{"raw", "*.md"}},
+			want: []string{"assets/logo.psd", "docs/notes.txt"},
+		},
+		{
+			name: "include",
+			cfg:  Config{Files: true, Include: []string{"docs/**/*.md"}},
+			want: []string{"docs/api/index.md", "docs/guide.md"},
+		},
+		{
+			name: "include and ignore",
+			cfg:  Config{Files: true, Include: []string{"*.psd"}, Ignore: []string{"assets/raw"}},
+			want: []string{"assets/icons/raw/b.psd", "assets/logo.psd"},
+		},
+		{
+			name: "extensions",
+			cfg:  Config{Files: true, Directories: true, Extensions: []string{"md", "png"}},
+			want: []string{"README.md", "assets", "assets/icons", "assets/icons/raw", "assets/icons/raw/b.png", "assets/raw", "docs", "docs/api", "docs/api/index.md", "docs/guide.md"},
+		},
+		{
+			name: "match",
+			cfg:  Config{Files: true, Extensions: []string{"md"}, Match: `^[a-z]`},
+			want: []string{"docs/api/index.md", "docs/guide.md"},
+		},
+		{
+			name: "include directories",
+			cfg:  Config{Directories: true, Include: []string{"**/raw"}},
+			want: []string{"assets/icons/raw", "assets/raw"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Path = root
+
+			got, err := Walk(cfg)
+			assert.Nil(t, err)
+
+			for i := range got {
+				rel, _ := filepath.Rel(root, got[i])
+				got[i] = filepath.ToSlash(rel)
+			}
+			sort.Strings(got)
+
+			assert.SliceEqual(t, got, tt.want)
+		})
+	}
+
+	_, err := Walk(Config{Path: root, Files: true, Include: []string{"[a-"}})
+	assert.NotNil(t, err)
+}
+
+func TestWalkDepth(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root, []string{
+		"a.txt",
+		"one/b.txt",
+		"one/two/c.txt",
+		"one/two/three/d.txt",
+	})
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{
+			name: "no limit",
+			cfg:  Config{Files: true, Directories: true},
+			want: []string{"a.txt", "one", "one/b.txt", "one/two", "one/two/c.txt", "one/two/three", "one/two/three/d.txt"},
+		},
+		{
+			name: "max depth",
+			cfg:  Config{MaxDepth: 2, Files: true, Directories: true},
+			want: []string{"a.txt", "one", "one/b.txt", "one/two"},
+		},
+		{
+			name: "min depth",
+			cfg:  Config{MinDepth: 3, Files: true, Directories: true},
+			want: []string{"one/two/c.txt", "one/two/three", "one/two/three/d.txt"},
+		},
+		{
+			name: "min and max depth",
+			cfg:  Config{MinDepth: 2, MaxDepth: 3, Files: true},
+			want: []string{"one/b.txt", "one/two/c.txt"},
+		},
+		{
+			name: "min depth beyond max depth",
+			cfg:  Config{MinDepth: 2, MaxDepth: 1, Files: true},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Path = root
+
+			got, err := Walk(cfg)
+			assert.Nil(t, err)
+
+			for i := range got {
+				rel, _ := filepath.Rel(root, got[i])
+				got[i] = filepath.ToSlash(rel)
+			}
+			sort.Strings(got)
+
+			assert.SliceEqual(t, got, tt.want)
+		})
+	}
+}
+
 func TestWalkSingleFile(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -271,3 +577,13 @@ func createFiles(t *testing.T, root string, files []string) {
 		}
 	}
 }
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}